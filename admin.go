@@ -0,0 +1,247 @@
+package simplelog
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// adminState represents the state of the log service as reported by the admin handler.
+type adminState struct {
+	Destinations []destinationState `json:"destinations"` // the state of the log destinations
+	Overrides    string             `json:"overrides"`    // the level overrides, see SetLevelOverrides
+	Queue        queueState         `json:"queue"`        // the state of the queue of the log service
+}
+
+// destinationState represents the state of a log destination as reported by the admin handler.
+type destinationState struct {
+	Name          string   `json:"name"`                    // "stdout", "file" or the name of a named log file
+	Level         string   `json:"level"`                   // the minimum level of log records
+	Prefix        []string `json:"prefix"`                  // the prefix of log records
+	Format        string   `json:"format"`                  // "text", "json", "logfmt" or "custom"
+	File          string   `json:"file,omitempty"`          // the name of the log file, if the log destination is a log file
+	Size          int64    `json:"size,omitempty"`          // the number of bytes written to the log file since it was opened
+	MaxSize       int64    `json:"maxSize,omitempty"`       // the size at which the log file is rotated, see SetRotation
+	Lines         string   `json:"lines"`                   // the line policy: "raw", "escape" or "indent"
+	StripANSI     bool     `json:"stripAnsi,omitempty"`     // true, if ANSI escape sequences are removed from log file records
+	MaxRecordSize int      `json:"maxRecordSize,omitempty"` // the size at which log records are truncated, see SetMaxRecordSize
+}
+
+// queueState represents the state of the queue of the log service as reported by the admin handler.
+type queueState struct {
+	Mode      string `json:"mode"`      // "channel", "sharded" or "sync", see Startup, StartupSharded and StartupSync
+	Length    int    `json:"length"`    // the number of pending log messages
+	Capacity  int    `json:"capacity"`  // the number of log messages which can be buffered
+	BatchSize int    `json:"batchSize"` // the maximum number of log messages written at once, see SetBatchSize
+}
+
+// state fills in the state of the log service. It is called by the log service, hence the state is
+// consistent with the log messages written so far.
+func (s *simpleLogService) state(st *adminState) {
+	st.Destinations = append(st.Destinations, destinationState{
+		Name:          "stdout",
+		Level:         s.stdoutLogger.level.String(),
+		Prefix:        s.stdoutLogger.prefix,
+		Format:        formatterName(s.stdoutLogger.formatter),
+		Lines:         s.stdoutLogger.lines.String(),
+		MaxRecordSize: s.stdoutLogger.maxRecordSize,
+	})
+	if s.fileLogger.desc != nil {
+		st.Destinations = append(st.Destinations, s.fileLogger.state("file"))
+	}
+	names := s.names()
+	sort.Strings(names)
+	for _, name := range names {
+		destination, _ := s.destination(name)
+		if f := s.namedFileLoggers[destination]; f != nil && f.desc != nil {
+			st.Destinations = append(st.Destinations, f.state(name))
+		}
+	}
+	for _, name := range names {
+		destination, _ := s.destination(name)
+		if r := s.rings[destination]; r != nil {
+			st.Destinations = append(st.Destinations, destinationState{Name: name, Level: r.level.String(), Prefix: ringPrefix, Format: "text", Lines: RawLines.String()})
+		}
+	}
+	if o, _ := s.levelOverrides.Load().(*levelOverrides); o != nil {
+		st.Overrides = o.spec
+	}
+	st.Queue.BatchSize = s.batchSize
+	switch {
+	case s.synchronous:
+		st.Queue.Mode = "sync"
+	case s.shardedQueue != nil:
+		st.Queue.Mode = "sharded"
+		st.Queue.Length = s.shardedQueue.len()
+		st.Queue.Capacity = int(s.shardedQueue.capacity)
+	default:
+		st.Queue.Mode = "channel"
+		st.Queue.Length = len(s.dataQueue)
+		st.Queue.Capacity = cap(s.dataQueue)
+	}
+}
+
+// state returns the state of a file log destination.
+func (f *fileLogger) state(name string) destinationState {
+	return destinationState{
+		Name:          name,
+		Level:         f.level.String(),
+		Prefix:        f.prefix,
+		Format:        formatterName(f.formatter),
+		File:          f.desc.Name(),
+		Size:          f.size,
+		MaxSize:       f.maxSize,
+		Lines:         f.lines.String(),
+		StripANSI:     f.stripANSI,
+		MaxRecordSize: f.maxRecordSize,
+	}
+}
+
+// formatterName returns the name of a formatter as used by the configuration, see DestinationConfig.
+func formatterName(formatter Formatter) string {
+	switch formatter.(type) {
+	case nil, TextFormatter, *TextFormatter:
+		return "text"
+	case JSONFormatter, *JSONFormatter:
+		return "json"
+	case LogfmtFormatter, *LogfmtFormatter:
+		return "logfmt"
+	}
+	return "custom"
+}
+
+// adminHandler implements the http.Handler returned by AdminHandler.
+type adminHandler struct{}
+
+// AdminHandler returns an http.Handler to inspect and change the log service at runtime, e.g. by
+//
+//	http.Handle("/debug/log/", http.StripPrefix("/debug/log", simplelog.AdminHandler()))
+//
+// The handler serves the following requests relative to its path:
+//
+//	GET /                                     the state of the log service as JSON
+//	PUT /level?destination=<name>&level=<level> sets the level of a log destination, see SetLevel
+//	PUT /prefix?destination=<name>            sets the prefix of a log destination to the JSON array of the body, see SetPrefix
+//	PUT /overrides                            sets the level overrides to the body, e.g. "http*=debug", see SetLevelOverrides
+//	PUT /rotate?destination=<name>            rotates a log file at once
+//	PUT /flush                                writes all pending log messages and commits the log files, see Sync
+//	PUT /reopen                               reopens all log files, see Reopen
+//
+// A log destination is denoted by "stdout", "file" or the name of a named log file. Successful PUT requests
+// respond with the new state of the log service. All requests are passed to the log service like the calls
+// of the respective functions, hence they are serialized with the log messages written.
+// The handler doesn't authenticate requests; it must only be reachable by operators.
+func AdminHandler() http.Handler {
+	return adminHandler{}
+}
+
+// ServeHTTP denotes the http.Handler interface implementation by the adminHandler type.
+func (h adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.isActive() {
+		http.Error(w, sg002, http.StatusServiceUnavailable)
+		return
+	}
+	action := strings.Trim(r.URL.Path, "/")
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+	case action == "":
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	case r.Method == http.MethodPut:
+		if status, err := h.put(action, r); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	case adminActions[action]:
+		w.Header().Set("Allow", http.MethodPut)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	st := new(adminState)
+	if err := s.configure(configMessage{getstate, map[int]any{logstate: st}}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+// adminActions lists the paths of the PUT requests served by the admin handler.
+var adminActions = map[string]bool{"level": true, "prefix": true, "overrides": true, "rotate": true, "flush": true, "reopen": true}
+
+// put handles a PUT request of the admin handler. If the request fails, the HTTP status code to respond with
+// and the error are returned.
+func (adminHandler) put(action string, r *http.Request) (int, error) {
+	var err error
+	switch action {
+	case "level":
+		destination, err := adminDestination(r, false, true)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		level, ok := ParseLevel(r.URL.Query().Get("level"))
+		if !ok {
+			return http.StatusBadRequest, errors.New("unknown log level specified")
+		}
+		SetLevel(destination, level)
+	case "prefix":
+		destination, err := adminDestination(r, false, false)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		var prefix []string
+		if err = json.NewDecoder(r.Body).Decode(&prefix); err != nil {
+			return http.StatusBadRequest, errors.New("prefix is not a JSON array of strings: " + err.Error())
+		}
+		SetPrefix(destination, prefix...)
+	case "overrides":
+		var spec []byte
+		if spec, err = io.ReadAll(r.Body); err == nil {
+			err = SetLevelOverrides(string(spec))
+		}
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+	case "rotate":
+		var destination int
+		if destination, err = adminDestination(r, true, false); err != nil {
+			return http.StatusBadRequest, err
+		}
+		err = s.configure(configMessage{rotatelog, map[int]any{logdestination: destination}})
+	case "flush":
+		err = s.configure(configMessage{synclog, nil})
+	case "reopen":
+		err = reopen()
+	default:
+		return http.StatusNotFound, errors.New(http.StatusText(http.StatusNotFound))
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// adminDestination returns the log destination denoted by the destination parameter of a request.
+// If fileOnly is true, only log file destinations are accepted; ring buffers are only accepted, if rings is true.
+func adminDestination(r *http.Request, fileOnly, rings bool) (int, error) {
+	name := r.URL.Query().Get("destination")
+	destination, ok := FILE, true
+	switch name {
+	case "stdout":
+		destination = STDOUT
+	case "file":
+	default:
+		destination, ok = s.destination(name)
+	}
+	if !ok || fileOnly && !s.isFileDestination(destination) || !rings && s.isRingDestination(destination) {
+		return 0, errors.New(sg003)
+	}
+	return destination, nil
+}
@@ -0,0 +1,384 @@
+// Command simplelog reads log files written by simplelog, including their archives, and filters, converts,
+// merges or follows them.
+//
+// Usage:
+//
+//	simplelog cat [flags] [file ...]     reads the log files in turn, or the standard input without files
+//	simplelog merge [flags] file ...     merges the log records of the log files by their time
+//	simplelog follow [flags] file        reads the log records written to a log file from now on, surviving rotations
+//
+// The flags are:
+//
+//	-in format            the format of the log files: text, json or logfmt (default text)
+//	-prefix element       an element of the prefix of the text format, repeated for each element, e.g.
+//	                      -prefix '#2006-01-02 15:04:05.000000#' -prefix '[%LEVEL%]'
+//	-out format           the output format: text, json or logfmt (default: the format of the log files)
+//	-out-prefix element   an element of the prefix of the text output (default: the prefix of the log files)
+//	-from time            only log records at or after the time, e.g. 2024-05-17T10:30:00Z or "2024-05-17 10:30:00"
+//	-to time              only log records before the time
+//	-level level          only log records of the level or above, e.g. warn
+//	-field key=value      only log records with the field, repeated for several fields; "component" matches the component
+//	-seek                 assumes the log records are ordered by time: jumps to -from by binary search within the log
+//	                      files and stops at -to instead of reading the log files completely
+//	-rotated              reads the archives of each log file (<file>_yyyymmddHHMMSS[.N][.gz]) before the log file itself
+//	-strict               stops at lines which can't be parsed instead of passing them through as messages
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/sabitor/simplelog"
+	"github.com/sabitor/simplelog/reader"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// listFlag is a flag which may be repeated, e.g. -prefix.
+type listFlag []string
+
+// String denotes the flag.Value interface implementation by the listFlag type.
+func (l *listFlag) String() string {
+	return strings.Join(*l, " ")
+}
+
+// Set denotes the flag.Value interface implementation by the listFlag type.
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// command represents the parsed command line.
+type command struct {
+	name      string              // the command: cat, merge or follow
+	files     []string            // the log files
+	opts      reader.Options      // the options to read the log files
+	rotated   bool                // true, if the archives of the log files are read as well
+	seek      bool                // true, if the time range is searched instead of filtered, see reader.Reader.SeekTime
+	formatter simplelog.Formatter // the formatter of the output
+	prefix    []string            // the prefix of the text output
+	from, to  time.Time           // the time range of the log records; zero means unbounded
+	level     simplelog.Level     // the minimum level of the log records
+	hasLevel  bool                // true, if the log records are filtered by level
+	fields    map[string]string   // the fields the log records must have
+}
+
+// run runs the command line and returns the exit code.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cmd, err := parseArgs(args, stderr)
+	if err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(stderr, "simplelog:", err)
+		}
+		return 2
+	}
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+	switch cmd.name {
+	case "cat":
+		err = cmd.cat(stdin, out)
+	case "merge":
+		err = cmd.merge(out)
+	case "follow":
+		err = cmd.follow(ctx, out)
+	}
+	if err != nil {
+		out.Flush()
+		fmt.Fprintln(stderr, "simplelog:", err)
+		return 1
+	}
+	return 0
+}
+
+// parseArgs parses the command line.
+func parseArgs(args []string, stderr io.Writer) (*command, error) {
+	if len(args) == 0 {
+		return nil, errors.New("missing command: cat, merge or follow")
+	}
+	cmd := &command{name: args[0]}
+	switch cmd.name {
+	case "cat", "merge", "follow":
+	default:
+		return nil, errors.New("unknown command " + cmd.name + ": expected cat, merge or follow")
+	}
+	var inFormat, outFormat, from, to, level string
+	var prefix, outPrefix, fields listFlag
+	fs := flag.NewFlagSet("simplelog "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&inFormat, "in", "text", "the `format` of the log files: text, json or logfmt")
+	fs.Var(&prefix, "prefix", "an `element` of the prefix of the text format, repeated for each element")
+	fs.StringVar(&outFormat, "out", "", "the output `format`: text, json or logfmt (default: the format of the log files)")
+	fs.Var(&outPrefix, "out-prefix", "an `element` of the prefix of the text output (default: the prefix of the log files)")
+	fs.StringVar(&from, "from", "", "only log records at or after the `time`")
+	fs.StringVar(&to, "to", "", "only log records before the `time`")
+	fs.StringVar(&level, "level", "", "only log records of the `level` or above")
+	fs.Var(&fields, "field", "only log records with the field `key=value`, repeated for several fields")
+	fs.BoolVar(&cmd.seek, "seek", false, "jump to -from and stop at -to, assuming the log records are ordered by time")
+	fs.BoolVar(&cmd.rotated, "rotated", false, "read the archives of each log file before the log file itself")
+	fs.BoolVar(&cmd.opts.Strict, "strict", false, "stop at lines which can't be parsed")
+	if err := fs.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+	cmd.files = fs.Args()
+	switch {
+	case cmd.name == "merge" && len(cmd.files) == 0:
+		return nil, errors.New("merge needs at least one log file")
+	case cmd.name == "follow" && len(cmd.files) != 1:
+		return nil, errors.New("follow needs exactly one log file")
+	case cmd.name == "follow" && cmd.rotated:
+		return nil, errors.New("-rotated can't be used with follow")
+	case cmd.name == "follow" && cmd.seek:
+		return nil, errors.New("-seek can't be used with follow")
+	}
+
+	var err error
+	if cmd.opts.Format, err = parseFormat(inFormat); err != nil {
+		return nil, err
+	}
+	if outFormat == "" {
+		outFormat = inFormat
+	}
+	outFmt, err := parseFormat(outFormat)
+	if err != nil {
+		return nil, err
+	}
+	cmd.formatter = [...]simplelog.Formatter{reader.Text: simplelog.TextFormatter{}, reader.JSON: simplelog.JSONFormatter{}, reader.Logfmt: simplelog.LogfmtFormatter{}}[outFmt]
+	cmd.opts.Prefix, cmd.prefix = prefix, outPrefix
+	if cmd.prefix == nil {
+		cmd.prefix = prefix
+	}
+	if from != "" {
+		if cmd.from, err = parseTime(from); err != nil {
+			return nil, err
+		}
+	}
+	if to != "" {
+		if cmd.to, err = parseTime(to); err != nil {
+			return nil, err
+		}
+	}
+	if level != "" {
+		var ok bool
+		if cmd.level, ok = simplelog.ParseLevel(level); !ok {
+			return nil, errors.New("unknown log level " + level)
+		}
+		cmd.hasLevel = true
+	}
+	cmd.fields = make(map[string]string, len(fields))
+	for _, f := range fields {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok || key == "" {
+			return nil, errors.New("field " + f + " is not of the form key=value")
+		}
+		cmd.fields[key] = value
+	}
+	return cmd, nil
+}
+
+// parseFormat returns the log file format of a name.
+func parseFormat(name string) (reader.Format, error) {
+	switch strings.ToLower(name) {
+	case "text":
+		return reader.Text, nil
+	case "json":
+		return reader.JSON, nil
+	case "logfmt":
+		return reader.Logfmt, nil
+	}
+	return 0, errors.New("unknown format " + name + ": expected text, json or logfmt")
+}
+
+// timeLayouts lists the layouts accepted by -from and -to; times without time zone are local times.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+// parseTime parses the time of -from and -to.
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("invalid time " + value + ": expected e.g. 2006-01-02T15:04:05Z07:00 or 2006-01-02 15:04:05")
+}
+
+// open returns a reader of a log file, including its archives, if requested. With -seek, the reader is
+// advanced to the first log record of the time range.
+func (cmd *command) open(path string) (*reader.Reader, error) {
+	var r *reader.Reader
+	var err error
+	if cmd.rotated {
+		r, err = reader.OpenRotated(path, cmd.opts)
+	} else {
+		r, err = reader.Open(path, cmd.opts)
+	}
+	if err == nil && cmd.seek && !cmd.from.IsZero() {
+		if err = r.SeekTime(cmd.from); err != nil {
+			r.Close()
+		}
+	}
+	return r, err
+}
+
+// done returns true, if the log records following a log record are beyond the time range. This is only
+// assumed with -seek, since log records can't be expected to be ordered by time otherwise.
+func (cmd *command) done(rec *simplelog.Record) bool {
+	return cmd.seek && !cmd.to.IsZero() && !rec.Time.Before(cmd.to) && !rec.Time.IsZero()
+}
+
+// cat writes the matching log records of the log files in turn, or of the standard input without log files.
+func (cmd *command) cat(stdin io.Reader, out *bufio.Writer) error {
+	if len(cmd.files) == 0 {
+		r := reader.NewReader(stdin, cmd.opts)
+		if cmd.seek && !cmd.from.IsZero() {
+			r.SeekTime(cmd.from)
+		}
+		return cmd.copy(r, out, false)
+	}
+	for _, path := range cmd.files {
+		r, err := cmd.open(path)
+		if err != nil {
+			return err
+		}
+		err = cmd.copy(r, out, false)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// follow writes the matching log records written to the log file from now on, until the context is done.
+func (cmd *command) follow(ctx context.Context, out *bufio.Writer) error {
+	r, err := reader.Follow(ctx, cmd.files[0], cmd.opts)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return cmd.copy(r, out, true)
+}
+
+// copy writes the matching log records of a reader. If flush is true, each log record is flushed at once.
+func (cmd *command) copy(r *reader.Reader, out *bufio.Writer, flush bool) error {
+	var buf []byte
+	for r.Next() {
+		rec := r.Record()
+		if cmd.done(&rec) {
+			break
+		}
+		if !cmd.match(&rec) {
+			continue
+		}
+		buf = cmd.format(buf[:0], &rec)
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+		if flush {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return r.Err()
+}
+
+// merge writes the matching log records of all log files ordered by their time. Log records with the same
+// time are written in the order of the log files on the command line.
+func (cmd *command) merge(out *bufio.Writer) error {
+	readers := make([]*reader.Reader, 0, len(cmd.files))
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+	// heads holds the next log record of each reader; a nil head denotes an exhausted reader
+	heads := make([]*simplelog.Record, len(cmd.files))
+	next := func(i int) error {
+		heads[i] = nil
+		for readers[i].Next() {
+			rec := readers[i].Record()
+			if cmd.done(&rec) {
+				break
+			}
+			if cmd.match(&rec) {
+				heads[i] = &rec
+				break
+			}
+		}
+		return readers[i].Err()
+	}
+	for i, path := range cmd.files {
+		r, err := cmd.open(path)
+		if err != nil {
+			return err
+		}
+		readers = append(readers, r)
+		if err = next(i); err != nil {
+			return err
+		}
+	}
+	var buf []byte
+	for {
+		first := -1
+		for i, rec := range heads {
+			if rec != nil && (first < 0 || rec.Time.Before(heads[first].Time)) {
+				first = i
+			}
+		}
+		if first < 0 {
+			return nil
+		}
+		buf = cmd.format(buf[:0], heads[first])
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+		if err := next(first); err != nil {
+			return err
+		}
+	}
+}
+
+// match returns true, if a log record matches the filters of the command line.
+func (cmd *command) match(rec *simplelog.Record) bool {
+	if !cmd.from.IsZero() && rec.Time.Before(cmd.from) || !cmd.to.IsZero() && !rec.Time.Before(cmd.to) {
+		return false
+	}
+	if cmd.hasLevel && rec.Level < cmd.level {
+		return false
+	}
+	for key, value := range cmd.fields {
+		if !hasField(rec, key, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasField returns true, if a log record has a field with the given key and value. The key "component" matches
+// the component of the log record, unless the log record has a field of this key.
+func hasField(rec *simplelog.Record, key, value string) bool {
+	for _, f := range rec.Fields {
+		if f.Key == key {
+			return fmt.Sprint(f.Value()) == value
+		}
+	}
+	return key == "component" && rec.Component == value
+}
+
+// format appends a log record formatted in the output format to buf.
+func (cmd *command) format(buf []byte, rec *simplelog.Record) []byte {
+	rec.Prefix = cmd.prefix
+	return cmd.formatter.Format(buf, rec)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCatAndMerge(t *testing.T) {
+	dir := t.TempDir()
+	log1 := filepath.Join(dir, "a.log")
+	log2 := filepath.Join(dir, "b.log")
+	os.WriteFile(log1, []byte("\n"+
+		"2024-05-17 10:00:00.000000 [INFO] started port=80\n"+
+		"2024-05-17 10:00:02.000000 [DEBUG] [http] request path=/a\n"+
+		"2024-05-17 10:00:04.000000 [ERROR] [http] request failed path=/b\n"), 0644)
+	os.WriteFile(log2, []byte(
+		"2024-05-17 10:00:01.000000 [WARN] disk almost full\n"+
+			"2024-05-17 10:00:03.000000 [INFO] [http] request path=/c\n"), 0644)
+	prefix := []string{"-prefix", "#2006-01-02 15:04:05.000000#", "-prefix", "[%LEVEL%]"}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"filter", append(append([]string{"cat"}, prefix...), "-level", "info", "-field", "component=http", log1),
+			"2024-05-17 10:00:04.000000 [ERROR] [http] request failed path=/b\n"},
+		{"convert", append(append([]string{"cat"}, prefix...), "-out", "logfmt", "-from", "2024-05-17 10:00:01", "-to", "2024-05-17 10:00:04", log1),
+			"time=" + localTime("2024-05-17 10:00:02") + " level=DEBUG component=http msg=request path=/a\n"},
+		{"merge", append(append([]string{"merge"}, prefix...), "-out-prefix", "%LEVEL%", log1, log2),
+			"INFO started port=80\nWARN disk almost full\nDEBUG [http] request path=/a\nINFO [http] request path=/c\nERROR [http] request failed path=/b\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(context.Background(), tt.args, nil, &stdout, &stderr); code != 0 {
+				t.Fatalf("Expected exit code 0 but got %d: %s", code, stderr.String())
+			}
+			if stdout.String() != tt.want {
+				t.Errorf("Expected\n%q but got\n%q", tt.want, stdout.String())
+			}
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"cat", "-in", "xml", log1}, nil, &stdout, &stderr); code != 2 {
+		t.Error("Expected exit code 2 for an unknown format but got", code)
+	}
+}
+
+// localTime returns a local time in RFC 3339 format, as written by the LogfmtFormatter.
+func localTime(value string) string {
+	t, _ := parseTime(value)
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,126 @@
+package simplelog
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// ColorMode denotes whether the TextFormatter colors its output by ANSI escape sequences.
+type ColorMode int
+
+// color modes
+const (
+	ColorNever  ColorMode = iota // the output is plain text; this is the default
+	ColorAuto                    // the output is colored, if stdout is a terminal and the NO_COLOR environment variable isn't set
+	ColorAlways                  // the output is always colored, e.g. if it is piped into less -R
+)
+
+// ANSI escape sequences used to color the output
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+)
+
+// levelColors maps the named log levels to their colors; higher levels than ERROR are colored like FATAL.
+var levelColors = map[Level]string{
+	TRACE: "\x1b[90m", // gray
+	DEBUG: "\x1b[36m", // cyan
+	INFO:  "\x1b[32m", // green
+	WARN:  "\x1b[33m", // yellow
+	ERROR: "\x1b[31m", // red
+}
+
+// levelColor returns the ANSI escape sequence which colors a log level.
+func levelColor(level Level) string {
+	if c, ok := levelColors[level]; ok {
+		return c
+	}
+	if level > ERROR {
+		return "\x1b[1;31m" // bold red
+	}
+	return levelColors[TRACE]
+}
+
+// colorModeNames maps the color modes to their names as used by the configuration.
+var colorModeNames = map[ColorMode]string{ColorNever: "never", ColorAuto: "auto", ColorAlways: "always"}
+
+// String returns the name of the color mode, e.g. "auto".
+func (m ColorMode) String() string {
+	if name, ok := colorModeNames[m]; ok {
+		return name
+	}
+	return "never"
+}
+
+// colorModeByName returns the color mode of a name used in configurations; the empty name denotes ColorNever.
+func colorModeByName(name string) (ColorMode, bool) {
+	if name == "" {
+		return ColorNever, true
+	}
+	for m, n := range colorModeNames {
+		if strings.EqualFold(n, name) {
+			return m, true
+		}
+	}
+	return ColorNever, false
+}
+
+// stdoutColor caches whether ColorAuto colors the output, since stdout and the environment don't change.
+var stdoutColor struct {
+	once    sync.Once
+	enabled bool
+}
+
+// enabled returns true, if the output is colored in the color mode.
+func (m ColorMode) enabled() bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorAuto:
+		stdoutColor.once.Do(func() {
+			noColor, _ := os.LookupEnv("NO_COLOR")
+			stdoutColor.enabled = detectColor(os.Stdout, noColor)
+		})
+		return stdoutColor.enabled
+	}
+	return false
+}
+
+// isColored returns true, if the formatter is a TextFormatter which may color its output.
+func isColored(formatter Formatter) bool {
+	switch t := formatter.(type) {
+	case TextFormatter:
+		return t.Color != ColorNever
+	case *TextFormatter:
+		return t != nil && t.Color != ColorNever
+	}
+	return false
+}
+
+// detectColor returns true, if output written to a file is to be colored: the file has to be a terminal and
+// the value of the NO_COLOR environment variable has to be empty (see https://no-color.org).
+func detectColor(f *os.File, noColor string) bool {
+	if noColor != "" {
+		return false
+	}
+	return isTerminal(f.Fd())
+}
+
+// displayWidth returns the number of characters of b, not counting ANSI control sequences.
+func displayWidth(b []byte) int {
+	width := 0
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == 0x1b && i+1 < len(b) && b[i+1] == '[':
+			// skip the control sequence up to its final byte
+			for i += 2; i < len(b) && (b[i] < 0x40 || b[i] > 0x7e); i++ {
+			}
+		case c < 0x80 || c >= 0xc0:
+			// the first byte of a character
+			width++
+		}
+	}
+	return width
+}
@@ -0,0 +1,624 @@
+package simplelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// environment variables
+const (
+	envPrefix      = "SIMPLELOG_"         // prefix of all environment variables overriding the configuration
+	envNamedPrefix = envPrefix + "FILES_" // prefix of the environment variables of named log files
+	envListSep     = "|"                  // separates the elements of a prefix given by an environment variable
+)
+
+// Config represents a declarative configuration of the log service, which can be loaded from a JSON file
+// by LoadConfig and is applied by StartupConfig.
+//
+// A configuration file looks for example as follows:
+//
+//	{
+//	  "bufferSize": 100,
+//	  "stdout": {"prefix": ["#15:04:05#"], "level": "warn"},
+//	  "file": {"path": "app.log", "append": true, "prefix": ["#2006-01-02 15:04:05.000000#", "[%LEVEL%]"], "maxSize": 10485760},
+//	  "files": {"audit": {"path": "audit.log", "format": "json"}}
+//	}
+type Config struct {
+	BufferSize int                    `json:"bufferSize"` // number of log messages which can be buffered before the log service blocks
+	BatchSize  int                    `json:"batchSize"`  // maximum number of log messages written at once; 0 means the default
+	Shards     int                    `json:"shards"`     // number of shards of the queue, see StartupSharded; 0 means a single channel
+	Sync       bool                   `json:"sync"`       // write log messages in the caller's goroutine, see StartupSync
+	Overrides  string                 `json:"overrides"`  // level overrides like "http*=debug,db=warn", see SetLevelOverrides
+	Stdout     DestinationConfig      `json:"stdout"`     // configuration of the STDOUT log destination
+	File       *FileConfig            `json:"file"`       // configuration of the FILE log destination; nil if no log file is set up
+	Files      map[string]*FileConfig `json:"files"`      // configuration of the named log files, keyed by their name
+}
+
+// DestinationConfig represents the configuration every log destination has.
+type DestinationConfig struct {
+	Prefix []string `json:"prefix"` // prefix for each log record, see SetPrefix
+	Level  string   `json:"level"`  // minimum level of log records, e.g. "debug"; empty means INFO
+	Format string   `json:"format"` // format of log records: "text", "json" or "logfmt"; empty means "text"
+	Lines  string   `json:"lines"`  // line policy of messages: "raw", "escape" or "indent"; empty means "raw", see SetLinePolicy
+	Color  string   `json:"color"`  // color mode of the text format of stdout: "never", "auto" or "always"; empty means "never"
+	// maximum size in bytes of a log record; longer log records are truncated, see SetMaxRecordSize; 0 means no limit
+	MaxRecordSize int `json:"maxRecordSize"`
+}
+
+// FileConfig represents the configuration of a log file.
+type FileConfig struct {
+	DestinationConfig
+	Path      string `json:"path"`      // name of the log file
+	Append    bool   `json:"append"`    // append to an existing log file (true) or truncate it (false)
+	MaxSize   int64  `json:"maxSize"`   // size in bytes at which the log file is rotated; 0 disables the rotation
+	StripANSI bool   `json:"stripAnsi"` // remove ANSI escape sequences from log records, see SetStripANSI
+}
+
+// ConfigError reports all problems found in a configuration.
+type ConfigError struct {
+	Problems []string // descriptions of the problems found
+}
+
+// Error denotes the error interface implementation by the ConfigError type.
+func (e *ConfigError) Error() string {
+	return "invalid log configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// add adds a problem to the list of problems.
+func (e *ConfigError) add(problem string) {
+	e.Problems = append(e.Problems, problem)
+}
+
+// errorOrNil returns the ConfigError, if problems were found, nil otherwise.
+func (e *ConfigError) errorOrNil() error {
+	if len(e.Problems) > 0 {
+		return e
+	}
+	return nil
+}
+
+// LoadConfig loads a configuration from a JSON file and overrides it by environment variables.
+// If path is empty, the configuration is built from environment variables only.
+//
+// The following environment variables override the respective configuration values:
+//
+//	SIMPLELOG_BUFFER_SIZE, SIMPLELOG_BATCH_SIZE, SIMPLELOG_SHARDS, SIMPLELOG_SYNC, SIMPLELOG_OVERRIDES
+//	SIMPLELOG_STDOUT_PREFIX, SIMPLELOG_STDOUT_LEVEL, SIMPLELOG_STDOUT_FORMAT, SIMPLELOG_STDOUT_LINES,
+//	SIMPLELOG_STDOUT_COLOR, SIMPLELOG_STDOUT_MAX_RECORD_SIZE
+//	SIMPLELOG_FILE_PATH, SIMPLELOG_FILE_APPEND, SIMPLELOG_FILE_PREFIX, SIMPLELOG_FILE_LEVEL,
+//	SIMPLELOG_FILE_FORMAT, SIMPLELOG_FILE_LINES, SIMPLELOG_FILE_MAX_RECORD_SIZE, SIMPLELOG_FILE_MAX_SIZE,
+//	SIMPLELOG_FILE_STRIP_ANSI
+//	SIMPLELOG_FILES_<NAME>_PATH, SIMPLELOG_FILES_<NAME>_APPEND, ... for the named log file <name>
+//
+// The elements of a prefix are separated by |, e.g. SIMPLELOG_STDOUT_PREFIX="#15:04:05#|[app]".
+// The configuration is validated and all problems found are reported at once by a *ConfigError.
+func LoadConfig(path string) (*Config, error) {
+	cfg := new(Config)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err = dec.Decode(cfg); err != nil {
+			return nil, &ConfigError{[]string{path + ": " + err.Error()}}
+		}
+	}
+	problems := new(ConfigError)
+	cfg.applyEnv(os.Environ(), problems)
+	cfg.validate(problems)
+	if err := problems.errorOrNil(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the configuration and reports all problems found at once by a *ConfigError.
+func (c *Config) Validate() error {
+	problems := new(ConfigError)
+	c.validate(problems)
+	return problems.errorOrNil()
+}
+
+// validate adds all problems found in the configuration to problems.
+func (c *Config) validate(problems *ConfigError) {
+	if c.BufferSize < 0 {
+		problems.add("bufferSize must not be negative")
+	}
+	if c.BatchSize < 0 {
+		problems.add("batchSize must not be negative")
+	}
+	if c.Shards < 0 {
+		problems.add("shards must not be negative")
+	}
+	if _, err := parseLevelOverrides(c.Overrides); err != nil {
+		problems.add("overrides: " + err.Error())
+	}
+	if c.Sync && c.Shards > 0 {
+		problems.add("shards must not be set in synchronous mode")
+	}
+	c.Stdout.validate("stdout", problems)
+	paths := make(map[string]string)
+	if c.File != nil {
+		c.File.validate("file", paths, problems)
+	}
+	for _, name := range c.fileNames() {
+		if name == "" {
+			problems.add("files: the name of a named log file must not be empty")
+		}
+		if f := c.Files[name]; f == nil {
+			problems.add("files." + name + ": configuration is missing")
+		} else {
+			f.validate("files."+name, paths, problems)
+		}
+	}
+}
+
+// validate adds all problems found in the configuration of a log destination to problems.
+func (d *DestinationConfig) validate(key string, problems *ConfigError) {
+	if d.Level != "" {
+		if _, ok := ParseLevel(d.Level); !ok {
+			problems.add(key + ".level: unknown level " + strconv.Quote(d.Level))
+		}
+	}
+	if _, ok := formatterByName(d.Format); !ok {
+		problems.add(key + ".format: unknown format " + strconv.Quote(d.Format))
+	}
+	if _, ok := linePolicyByName(d.Lines); !ok {
+		problems.add(key + ".lines: unknown line policy " + strconv.Quote(d.Lines))
+	}
+	if mode, ok := colorModeByName(d.Color); !ok {
+		problems.add(key + ".color: unknown color mode " + strconv.Quote(d.Color))
+	} else if _, text := d.formatter().(TextFormatter); mode != ColorNever && !text {
+		problems.add(key + ".color: only supported by the text format")
+	}
+	if d.MaxRecordSize < 0 {
+		problems.add(key + ".maxRecordSize: must not be negative")
+	}
+}
+
+// validate adds all problems found in the configuration of a log file to problems.
+// The paths parameter collects the paths of all log files to detect log files used by multiple log destinations.
+func (f *FileConfig) validate(key string, paths map[string]string, problems *ConfigError) {
+	f.DestinationConfig.validate(key, problems)
+	if mode, ok := colorModeByName(f.Color); ok && mode != ColorNever {
+		problems.add(key + ".color: only supported by stdout")
+	}
+	if f.Path == "" {
+		problems.add(key + ".path: must not be empty")
+	} else if other, ok := paths[f.Path]; ok {
+		problems.add(key + ".path: " + strconv.Quote(f.Path) + " is already used by " + other)
+	} else {
+		paths[f.Path] = key
+	}
+	if f.MaxSize < 0 {
+		problems.add(key + ".maxSize: must not be negative")
+	}
+}
+
+// fileNames returns the names of the named log files in sorted order.
+func (c *Config) fileNames() []string {
+	names := make([]string, 0, len(c.Files))
+	for name := range c.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyEnv overrides the configuration by the SIMPLELOG_* variables found in environ, which is a list of
+// key=value pairs as returned by os.Environ. Values which cannot be parsed are added to problems.
+func (c *Config) applyEnv(environ []string, problems *ConfigError) {
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, envPrefix) {
+			continue
+		}
+		switch name := strings.TrimPrefix(key, envPrefix); {
+		case name == "BUFFER_SIZE":
+			if n, err := strconv.Atoi(value); err != nil {
+				problems.add(key + ": " + strconv.Quote(value) + " is not an integer")
+			} else {
+				c.BufferSize = n
+			}
+		case name == "BATCH_SIZE":
+			if n, err := strconv.Atoi(value); err != nil {
+				problems.add(key + ": " + strconv.Quote(value) + " is not an integer")
+			} else {
+				c.BatchSize = n
+			}
+		case name == "SHARDS":
+			if n, err := strconv.Atoi(value); err != nil {
+				problems.add(key + ": " + strconv.Quote(value) + " is not an integer")
+			} else {
+				c.Shards = n
+			}
+		case name == "OVERRIDES":
+			c.Overrides = value
+		case name == "SYNC":
+			if b, err := strconv.ParseBool(value); err != nil {
+				problems.add(key + ": " + strconv.Quote(value) + " is not a boolean")
+			} else {
+				c.Sync = b
+			}
+		case strings.HasPrefix(name, "STDOUT_"):
+			if !c.Stdout.applyEnv(key, strings.TrimPrefix(name, "STDOUT_"), value, problems) {
+				problems.add(key + ": unknown environment variable")
+			}
+		case strings.HasPrefix(key, envNamedPrefix):
+			fileName, setting, ok := cutLast(strings.TrimPrefix(key, envNamedPrefix), fileSettings)
+			if !ok || fileName == "" {
+				problems.add(key + ": unknown environment variable")
+				continue
+			}
+			f := c.namedFile(fileName)
+			f.applyEnv(key, setting, value, problems)
+		case strings.HasPrefix(name, "FILE_"):
+			if c.File == nil {
+				c.File = new(FileConfig)
+			}
+			c.File.applyEnv(key, strings.TrimPrefix(name, "FILE_"), value, problems)
+		default:
+			problems.add(key + ": unknown environment variable")
+		}
+	}
+}
+
+// fileSettings lists the suffixes of the environment variables of log files.
+var fileSettings = []string{"PATH", "APPEND", "PREFIX", "LEVEL", "FORMAT", "LINES", "COLOR", "MAX_RECORD_SIZE", "MAX_SIZE", "STRIP_ANSI"}
+
+// cutLast splits s of the form <head>_<setting> into head and setting, where setting is one of settings.
+func cutLast(s string, settings []string) (head, setting string, ok bool) {
+	for _, setting = range settings {
+		if strings.HasSuffix(s, "_"+setting) {
+			return strings.TrimSuffix(s, "_"+setting), setting, true
+		}
+	}
+	return "", "", false
+}
+
+// namedFile returns the configuration of the named log file whose upper-cased name is envName.
+// If there is no such log file, a new one with the lower-cased name is added to the configuration.
+func (c *Config) namedFile(envName string) *FileConfig {
+	for name, f := range c.Files {
+		if strings.ToUpper(name) == envName && f != nil {
+			return f
+		}
+	}
+	if c.Files == nil {
+		c.Files = make(map[string]*FileConfig)
+	}
+	f := new(FileConfig)
+	c.Files[strings.ToLower(envName)] = f
+	return f
+}
+
+// applyEnv overrides a setting of a log destination. Values which cannot be parsed are added to problems.
+// It returns false, if the setting is unknown.
+func (d *DestinationConfig) applyEnv(key, setting, value string, problems *ConfigError) bool {
+	switch setting {
+	case "PREFIX":
+		d.Prefix = strings.Split(value, envListSep)
+	case "LEVEL":
+		d.Level = value
+	case "FORMAT":
+		d.Format = value
+	case "LINES":
+		d.Lines = value
+	case "COLOR":
+		d.Color = value
+	case "MAX_RECORD_SIZE":
+		if n, err := strconv.Atoi(value); err != nil {
+			problems.add(key + ": " + strconv.Quote(value) + " is not an integer")
+		} else {
+			d.MaxRecordSize = n
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// applyEnv overrides a setting of a log file. Values which cannot be parsed are added to problems.
+func (f *FileConfig) applyEnv(key, setting, value string, problems *ConfigError) {
+	switch setting {
+	case "PATH":
+		f.Path = value
+	case "APPEND":
+		if b, err := strconv.ParseBool(value); err != nil {
+			problems.add(key + ": " + strconv.Quote(value) + " is not a boolean")
+		} else {
+			f.Append = b
+		}
+	case "MAX_SIZE":
+		if n, err := strconv.ParseInt(value, 10, 64); err != nil {
+			problems.add(key + ": " + strconv.Quote(value) + " is not an integer")
+		} else {
+			f.MaxSize = n
+		}
+	case "STRIP_ANSI":
+		if b, err := strconv.ParseBool(value); err != nil {
+			problems.add(key + ": " + strconv.Quote(value) + " is not a boolean")
+		} else {
+			f.StripANSI = b
+		}
+	default:
+		if !f.DestinationConfig.applyEnv(key, setting, value, problems) {
+			problems.add(key + ": unknown environment variable")
+		}
+	}
+}
+
+// formatterByName returns the formatter of a format name used in configurations.
+func formatterByName(name string) (Formatter, bool) {
+	switch strings.ToLower(name) {
+	case "", "text":
+		return TextFormatter{}, true
+	case "json":
+		return JSONFormatter{}, true
+	case "logfmt":
+		return LogfmtFormatter{}, true
+	}
+	return nil, false
+}
+
+// level returns the configured level, INFO if none is configured.
+func (d *DestinationConfig) level() Level {
+	level, _ := ParseLevel(d.Level)
+	return level
+}
+
+// formatter returns the configured formatter.
+func (d *DestinationConfig) formatter() Formatter {
+	formatter, _ := formatterByName(d.Format)
+	if _, ok := formatter.(TextFormatter); ok {
+		mode, _ := colorModeByName(d.Color)
+		return TextFormatter{Color: mode}
+	}
+	return formatter
+}
+
+// lines returns the configured line policy.
+func (d *DestinationConfig) lines() LinePolicy {
+	policy, _ := linePolicyByName(d.Lines)
+	return policy
+}
+
+// StartupConfig starts the log service and configures it according to a configuration.
+// It is the declarative counterpart of calling Startup, SetupLog, SetupLogNamed, SetPrefix, SetLevel,
+// SetFormatter, SetLinePolicy, SetMaxRecordSize, SetStripANSI, SetRotation, SetBatchSize and SetLevelOverrides. The log service has to be stopped by calling Shutdown.
+// If the configuration is invalid, StartupConfig panics with a *ConfigError before the log service is started.
+func StartupConfig(cfg *Config) {
+	if err := cfg.Validate(); err != nil {
+		panic(err)
+	}
+	if cfg.Sync {
+		StartupSync()
+	} else {
+		startup(cfg.BufferSize, cfg.Shards)
+	}
+	if cfg.BatchSize > 0 {
+		SetBatchSize(cfg.BatchSize)
+	}
+	if cfg.Overrides != "" {
+		SetLevelOverrides(cfg.Overrides)
+	}
+	cfg.Stdout.apply(STDOUT)
+	if cfg.File != nil {
+		SetupLog(cfg.File.Path, cfg.File.Append)
+		cfg.File.apply(FILE)
+	}
+	for _, name := range cfg.fileNames() {
+		f := cfg.Files[name]
+		f.apply(SetupLogNamed(name, f.Path, f.Append))
+	}
+}
+
+// apply applies the configuration to a log destination.
+func (d *DestinationConfig) apply(destination int) {
+	if d.Prefix != nil {
+		SetPrefix(destination, d.Prefix...)
+	}
+	SetLevel(destination, d.level())
+	SetFormatter(destination, d.formatter())
+	SetLinePolicy(destination, d.lines())
+	SetMaxRecordSize(destination, d.MaxRecordSize)
+}
+
+// apply applies the configuration to a log file destination.
+func (f *FileConfig) apply(destination int) {
+	f.DestinationConfig.apply(destination)
+	SetRotation(destination, f.MaxSize)
+	SetStripANSI(destination, f.StripANSI)
+}
+
+// configure applies the configuration of a log destination to the stdout logger.
+func (sl *stdoutLogger) configure(d *DestinationConfig) {
+	sl.prefix = d.Prefix
+	sl.level = d.level()
+	sl.formatter = d.formatter()
+	sl.lines = d.lines()
+	sl.maxRecordSize = d.MaxRecordSize
+}
+
+// configure applies the configuration of a log file to the file logger.
+func (f *fileLogger) configure(fc *FileConfig) {
+	f.prefix = fc.Prefix
+	f.level = fc.level()
+	f.formatter = fc.formatter()
+	f.maxSize = fc.MaxSize
+	f.lines = fc.lines()
+	f.maxRecordSize = fc.MaxRecordSize
+	f.stripANSI = fc.StripANSI
+}
+
+// applyConfig applies a configuration to the running log service.
+// The destinations parameter contains the log destinations of all named log files of the configuration.
+// All log files whose path changed or which are new are opened first; if this fails, the log files opened
+// so far are closed again and the current configuration is kept. Otherwise, log files which are replaced or
+// no longer configured are closed (not archived) and the settings of all log destinations are updated.
+// If the configuration leaves out the FILE log destination, its log file and settings are kept.
+func (s *simpleLogService) applyConfig(cfg *Config, destinations map[string]int) error {
+	var err error
+	opened := make(map[int]*fileLogger)
+	needsSetup := func(f *fileLogger, fc *FileConfig) bool {
+		return f == nil || f.desc == nil || f.desc.Name() != fc.Path
+	}
+	setup := func(destination int, fc *FileConfig) {
+		if err != nil {
+			return
+		}
+		flag := os.O_TRUNC | os.O_CREATE | os.O_WRONLY
+		if fc.Append {
+			flag = os.O_APPEND | os.O_CREATE | os.O_WRONLY
+		}
+		f := new(fileLogger)
+		if err = f.setupLogFile(flag, fc.Path); err == nil {
+			opened[destination] = f
+		}
+	}
+
+	// open new log files
+	if cfg.File != nil && needsSetup(&s.fileLogger, cfg.File) {
+		setup(FILE, cfg.File)
+	}
+	for name, destination := range destinations {
+		if needsSetup(s.namedFileLoggers[destination], cfg.Files[name]) {
+			setup(destination, cfg.Files[name])
+		}
+	}
+	if err != nil {
+		for _, f := range opened {
+			f.releaseFileLogger(false)
+		}
+		return err
+	}
+
+	// swap log files and apply settings
+	s.batchSize = defaultBatchSize
+	if cfg.BatchSize > 0 {
+		s.batchSize = cfg.BatchSize
+	}
+	overrides, _ := parseLevelOverrides(cfg.Overrides)
+	s.levelOverrides.Store(overrides)
+	s.stdoutLogger.configure(&cfg.Stdout)
+	if cfg.File != nil {
+		// FILE stays a valid log destination, hence a log file which is left out by the configuration is kept
+		if f, ok := opened[FILE]; ok {
+			s.fileLogger.releaseFileLogger(false)
+			s.fileLogger.desc, s.fileLogger.flag, s.fileLogger.size = f.desc, f.flag, f.size
+		}
+		s.fileLogger.configure(cfg.File)
+	}
+	configured := make(map[int]bool)
+	for name, destination := range destinations {
+		configured[destination] = true
+		if f, ok := opened[destination]; ok {
+			if old := s.namedFileLoggers[destination]; old != nil {
+				old.releaseFileLogger(false)
+			}
+			if s.namedFileLoggers == nil {
+				s.namedFileLoggers = make(map[int]*fileLogger)
+			}
+			s.namedFileLoggers[destination] = f
+		}
+		s.namedFileLoggers[destination].configure(cfg.Files[name])
+	}
+	for destination, f := range s.namedFileLoggers {
+		if !configured[destination] {
+			f.releaseFileLogger(false)
+			delete(s.namedFileLoggers, destination)
+		}
+	}
+	return nil
+}
+
+// ApplyConfig applies a configuration to the running log service.
+// Changed prefixes, levels, level overrides, formats, rotation settings and log file paths take effect at once and atomically:
+// log messages written before are still written according to the old configuration, log messages written
+// afterwards according to the new one. Named log files which are no longer configured are closed; the log file
+// of the FILE log destination is kept, if the configuration leaves it out.
+// The buffer size, the number of shards and the synchronous mode can't be changed while the log service is running
+// and are ignored.
+// If the configuration is invalid, e.g. if a named log file has the name of a ring buffer, or a log file can't
+// be opened, the current configuration is kept and the error is returned.
+func ApplyConfig(cfg *Config) error {
+	if s.isActive() {
+		return apply(cfg)
+	} else {
+		panic(sg002)
+	}
+}
+
+// apply applies a configuration like ApplyConfig, but returns an error instead of panicking, if the log
+// service isn't running or is stopped concurrently.
+func apply(cfg *Config) error {
+	if !s.isActive() {
+		return errors.New(sg000)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	// named log files can't share the log destination of a ring buffer
+	problems := new(ConfigError)
+	for _, name := range cfg.fileNames() {
+		if destination, ok := s.destination(name); ok && s.isRingDestination(destination) {
+			problems.add("files." + name + ": name is already used by a ring buffer")
+		}
+	}
+	if err := problems.errorOrNil(); err != nil {
+		return err
+	}
+	var registered []string
+	destinations := make(map[string]int)
+	for _, name := range cfg.fileNames() {
+		destination, ok := s.destination(name)
+		if !ok {
+			destination = s.registerDestination(name)
+			registered = append(registered, name)
+		}
+		destinations[name] = destination
+	}
+	if err := s.configure(configMessage{applyconfig, map[int]any{logconfig: cfg, logdestinations: destinations}}); err != nil {
+		for _, name := range registered {
+			s.unregisterDestination(name)
+		}
+		return err
+	}
+	// release the log destinations of named log files which are no longer configured; ring buffers aren't
+	// part of the configuration and are kept
+	for _, name := range s.names() {
+		if _, ok := destinations[name]; !ok {
+			if destination, _ := s.destination(name); !s.isRingDestination(destination) {
+				s.unregisterDestination(name)
+			}
+		}
+	}
+	return nil
+}
+
+// Reload loads a configuration by LoadConfig and applies it by ApplyConfig.
+// If loading or applying the configuration fails, the current configuration is kept and the error is returned.
+func Reload(path string) error {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	return ApplyConfig(cfg)
+}
+
+// reload loads and applies a configuration like Reload, but returns an error instead of panicking, if the log
+// service isn't running or is stopped concurrently.
+func reload(path string) error {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	return apply(cfg)
+}
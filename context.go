@@ -0,0 +1,60 @@
+package simplelog
+
+import (
+	"context"
+)
+
+// contextKey is the key of the fields stored in a context by NewContext.
+type contextKey struct{}
+
+// NewContext returns a copy of the parent context which stores request-scoped fields, e.g. a request ID or a
+// trace ID. The fields are added to every log record written with the context by WriteCtx or LogCtx.
+// Fields already stored in the parent context are kept; the given fields are added behind them.
+// The ctx parameter specifies the parent context.
+// The fields parameter consists of zero or more fields that are stored in the context.
+func NewContext(ctx context.Context, fields ...Field) context.Context {
+	parent := contextFields(ctx)
+	return context.WithValue(ctx, contextKey{}, append(parent[:len(parent):len(parent)], fields...))
+}
+
+// contextFields returns the fields stored in a context by NewContext.
+func contextFields(ctx context.Context) []Field {
+	fields, _ := ctx.Value(contextKey{}).([]Field)
+	return fields
+}
+
+// WriteCtx writes a log message with the fields stored in a context (see NewContext) to a specified destination.
+// The log message is written with level INFO, see Write. If the buffer of the log service is full, WriteCtx blocks
+// until either the log service has taken a log message or the context is done, in which case the log message
+// is dropped.
+// The ctx parameter specifies the context of the log message.
+// The destination parameter specifies the log destination, where the data will be written to.
+// The logValues parameter consists of one or multiple values that are logged.
+func WriteCtx(ctx context.Context, destination int, values ...any) {
+	write(ctx, nil, INFO, destination, "", values, "")
+}
+
+// LogCtx writes a log message with a given level, the fields stored in a context (see NewContext) and typed
+// fields to a specified destination, see Log. The fields stored in the context are written in front of the
+// given fields. If the buffer of the log service is full, LogCtx blocks until either the log service has taken
+// a log message or the context is done, in which case the log message is dropped.
+// The ctx parameter specifies the context of the log message.
+// The level parameter specifies the level of the log message, e.g. DEBUG or ERROR.
+// The destination parameter specifies the log destination, where the data will be written to.
+// The msg parameter specifies the message that is logged.
+// The fields parameter consists of zero or more fields that are logged as key/value pairs.
+func LogCtx(ctx context.Context, level Level, destination int, msg string, fields ...Field) {
+	log(ctx, nil, level, destination, msg, fields)
+}
+
+// WriteCtx writes a log message with the component name and the bound fields of the logger and the fields
+// stored in a context to a specified destination, see the package level function WriteCtx.
+func (l *Logger) WriteCtx(ctx context.Context, destination int, values ...any) {
+	write(ctx, l, INFO, destination, "", values, "")
+}
+
+// LogCtx writes a log message with a given level, the component name and the bound fields of the logger,
+// the fields stored in a context and typed fields to a specified destination, see the package level function LogCtx.
+func (l *Logger) LogCtx(ctx context.Context, level Level, destination int, msg string, fields ...Field) {
+	log(ctx, l, level, destination, msg, fields)
+}
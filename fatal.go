@@ -0,0 +1,91 @@
+package simplelog
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+)
+
+var (
+	osExit = os.Exit // exits the program; replaced by tests
+)
+
+// Sync writes all pending log messages and commits all log files to stable storage.
+// Unlike Shutdown, the log service keeps running.
+func Sync() {
+	if s.isActive() {
+		if err := s.configure(configMessage{synclog, nil}); err != nil {
+			panic(err)
+		}
+	} else {
+		panic(sg002)
+	}
+}
+
+// Exit writes all pending log messages, commits all log files to stable storage (see Sync) and then exits
+// the program with the given status code. Deferred functions are not run.
+func Exit(code int) {
+	Sync()
+	osExit(code)
+}
+
+// Fatal writes a log message with level ERROR to a specified destination and exits the program with
+// status code 1 afterwards. Before the program exits, all pending log messages are written and all log
+// files are committed to stable storage, hence no log message gets lost.
+// The destination parameter specifies the log destination, where the data will be written to.
+// The logValues parameter consists of one or multiple values that are logged.
+func Fatal(destination int, values ...any) {
+	write(nil, nil, ERROR, destination, "", values, "")
+	Exit(1)
+}
+
+// Panic writes a log message with level ERROR to a specified destination and panics afterwards.
+// Before it panics, all pending log messages are written and all log files are committed to stable storage.
+// The panic value is the log message formatted as with fmt.Sprintln, without the trailing newline; lazy values
+// (see Write) are evaluated once in the caller's goroutine, for both the log message and the panic value.
+// The destination parameter specifies the log destination, where the data will be written to.
+// The logValues parameter consists of one or multiple values that are logged.
+func Panic(destination int, values ...any) {
+	resolved := append([]any(nil), values...)
+	resolveValues(resolved)
+	write(nil, nil, ERROR, destination, "", resolved, "")
+	Sync()
+	panic((&Record{Values: resolved}).Message())
+}
+
+// RecoverAndLog recovers from a panic, writes the panic value and the stack trace of the panicking
+// goroutine as log message with level ERROR to a specified destination and panics again with the same value.
+// Before it panics again, all pending log messages are written and all log files are committed to stable storage.
+// RecoverAndLog has to be called directly as deferred function, e.g.:
+//
+//	defer simplelog.RecoverAndLog(simplelog.FILE)
+//
+// The destination parameter specifies the log destination, where the data will be written to.
+func RecoverAndLog(destination int) {
+	if v := recover(); v != nil {
+		write(nil, nil, ERROR, destination, "", []any{"panic:", v}, captureStack(0))
+		Sync()
+		panic(v)
+	}
+}
+
+// captureStack returns the stack trace of the calling goroutine.
+// The skip parameter specifies the number of stack frames to skip, with 0 identifying the caller of captureStack.
+// For each stack frame, the function name and, indented by a tab, the file name and line are listed.
+func captureStack(skip int) string {
+	var stack []byte
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(skip+2, pcs)])
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, frame.Function...)
+		stack = append(stack, "()\n\t"...)
+		stack = append(stack, frame.File...)
+		stack = append(stack, ':')
+		stack = strconv.AppendInt(stack, int64(frame.Line), 10)
+		stack = append(stack, '\n')
+		if !more {
+			return string(stack)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package simplelog
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// field kinds
+const (
+	anyKind = iota
+	stringKind
+	intKind
+	uintKind
+	floatKind
+	boolKind
+	durationKind
+	timeKind
+	errorKind
+)
+
+// Field represents a typed key/value pair which is attached to a log record, e.g. by Log.
+// Fields are created by the typed constructors String, Int, Float64, Bool, Duration, Time, Err and Any.
+// Except for Any, the constructors store the value without converting it into an interface,
+// hence creating and logging fields doesn't allocate memory.
+type Field struct {
+	Key   string // the key of the field
+	kind  int    // the kind of the value, which defines how the value is stored
+	num   uint64 // the value of numeric, bool, duration and time fields
+	str   string // the value of string fields
+	value any    // the value of error and any fields, the location of time fields or the time, if out of range of num
+}
+
+// String returns a field with a string value.
+func String(key, value string) Field {
+	return Field{Key: key, kind: stringKind, str: value}
+}
+
+// Int returns a field with an int value.
+func Int(key string, value int) Field {
+	return Int64(key, int64(value))
+}
+
+// Int64 returns a field with an int64 value.
+func Int64(key string, value int64) Field {
+	return Field{Key: key, kind: intKind, num: uint64(value)}
+}
+
+// Uint64 returns a field with an uint64 value.
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, kind: uintKind, num: value}
+}
+
+// Float64 returns a field with a float64 value.
+func Float64(key string, value float64) Field {
+	return Field{Key: key, kind: floatKind, num: math.Float64bits(value)}
+}
+
+// Bool returns a field with a bool value.
+func Bool(key string, value bool) Field {
+	var num uint64
+	if value {
+		num = 1
+	}
+	return Field{Key: key, kind: boolKind, num: num}
+}
+
+// Duration returns a field with a time.Duration value.
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, kind: durationKind, num: uint64(value)}
+}
+
+// Time returns a field with a time.Time value.
+// The time is stored with nanosecond precision; its monotonic clock reading is dropped.
+// Times which can't be represented as nanoseconds since 1970 by an int64, i.e. before 1678 or after 2262,
+// e.g. the zero time, are stored as time.Time, which allocates memory.
+func Time(key string, value time.Time) Field {
+	if ns := value.UnixNano(); time.Unix(0, ns).Equal(value) {
+		return Field{Key: key, kind: timeKind, num: uint64(ns), value: value.Location()}
+	}
+	return Field{Key: key, kind: timeKind, value: value.Round(0)}
+}
+
+// Err returns a field with the key "error" and an error value.
+func Err(err error) Field {
+	return Field{Key: "error", kind: errorKind, value: err}
+}
+
+// Any returns a field with a value of any type. The value is formatted as with fmt.Sprint.
+// Prefer the typed constructors, since converting the value into an interface may allocate memory.
+func Any(key string, value any) Field {
+	return Field{Key: key, kind: anyKind, value: value}
+}
+
+// Value returns the value of the field, e.g. an int64 for fields created by Int or a time.Time for fields
+// created by Time.
+func (f Field) Value() any {
+	switch f.kind {
+	case stringKind:
+		return f.str
+	case intKind:
+		return int64(f.num)
+	case uintKind:
+		return f.num
+	case floatKind:
+		return math.Float64frombits(f.num)
+	case boolKind:
+		return f.num == 1
+	case durationKind:
+		return time.Duration(f.num)
+	case timeKind:
+		return f.time()
+	}
+	return f.value
+}
+
+// String returns the field formatted as key=value, as it is written by the TextFormatter.
+func (f Field) String() string {
+	return string(appendTextField(nil, f))
+}
+
+// time returns the value of a time field.
+func (f Field) time() time.Time {
+	if t, ok := f.value.(time.Time); ok {
+		return t
+	}
+	t := time.Unix(0, int64(f.num))
+	if loc, ok := f.value.(*time.Location); ok && loc != nil {
+		t = t.In(loc)
+	}
+	return t
+}
+
+// appendTextField appends a field formatted as key=value to buf, with the value quoted if needed.
+// It is used by the TextFormatter and the LogfmtFormatter.
+func appendTextField(buf []byte, f Field) []byte {
+	buf = append(buf, f.Key...)
+	buf = append(buf, '=')
+	switch f.kind {
+	case stringKind:
+		return appendLogfmtValue(buf, f.str)
+	case intKind:
+		return strconv.AppendInt(buf, int64(f.num), 10)
+	case uintKind:
+		return strconv.AppendUint(buf, f.num, 10)
+	case floatKind:
+		return strconv.AppendFloat(buf, math.Float64frombits(f.num), 'g', -1, 64)
+	case boolKind:
+		return strconv.AppendBool(buf, f.num == 1)
+	case durationKind:
+		return append(buf, time.Duration(f.num).String()...)
+	case timeKind:
+		return f.time().AppendFormat(buf, time.RFC3339Nano)
+	case errorKind:
+		if f.value == nil {
+			return append(buf, "<nil>"...)
+		}
+		return appendLogfmtValue(buf, f.value.(error).Error())
+	}
+	return appendLogfmtValue(buf, fmt.Sprint(f.value))
+}
+
+// appendJSONField appends a field formatted as "key":value to buf.
+// Numbers and bools are written as JSON numbers and bools, all other values as JSON strings.
+func appendJSONField(buf []byte, f Field) []byte {
+	buf = appendJSONString(buf, f.Key)
+	buf = append(buf, ':')
+	switch f.kind {
+	case stringKind:
+		return appendJSONString(buf, f.str)
+	case intKind:
+		return strconv.AppendInt(buf, int64(f.num), 10)
+	case uintKind:
+		return strconv.AppendUint(buf, f.num, 10)
+	case floatKind:
+		if v := math.Float64frombits(f.num); !math.IsInf(v, 0) && !math.IsNaN(v) {
+			return strconv.AppendFloat(buf, v, 'g', -1, 64)
+		}
+		buf = append(buf, '"')
+		buf = strconv.AppendFloat(buf, math.Float64frombits(f.num), 'g', -1, 64)
+		return append(buf, '"')
+	case boolKind:
+		return strconv.AppendBool(buf, f.num == 1)
+	case durationKind:
+		buf = append(buf, '"')
+		buf = append(buf, time.Duration(f.num).String()...)
+		return append(buf, '"')
+	case timeKind:
+		buf = append(buf, '"')
+		buf = f.time().AppendFormat(buf, time.RFC3339Nano)
+		return append(buf, '"')
+	case errorKind:
+		if f.value == nil {
+			return append(buf, "null"...)
+		}
+		return appendJSONString(buf, f.value.(error).Error())
+	}
+	return appendJSONString(buf, fmt.Sprint(f.value))
+}
@@ -0,0 +1,318 @@
+package simplelog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Record represents a log record, which is passed to a Formatter to be turned into a line of output.
+// Log records passed to a Formatter are pooled; a Formatter must not retain them or any of their slices.
+type Record struct {
+	Time      time.Time  // the time the log record was written
+	Level     Level      // the level of the log record
+	Prefix    []string   // the prefix of the log destination the record is formatted for
+	Component string     // the component name of the Logger which wrote the log record, if any
+	Msg       string     // the message of the log record, if it was written by Log
+	Format    string     // the format specifier of the values, if the log record was written by Writef
+	Values    []any      // the values that are logged; lazy values are already evaluated
+	Fields    []Field    // the fields that are logged as key/value pairs
+	Stack     string     // the stack trace attached to the log record, if any
+	Lines     LinePolicy // the line policy of the log destination the record is formatted for, see SetLinePolicy
+}
+
+// Message returns the message of the log record. If the log record has no message (Msg), the message is
+// built from the values formatted according to the format specifier as with fmt.Sprintf, or, if the log
+// record has no format specifier, as with fmt.Sprint, but with spaces always added between the values.
+func (r *Record) Message() string {
+	if r.Msg != "" || r.Format == "" && len(r.Values) == 0 {
+		return r.Msg
+	}
+	if r.Format != "" {
+		return fmt.Sprintf(r.Format, r.Values...)
+	}
+	return string(appendValues(nil, r.Values))
+}
+
+// appendMessage appends the message of the log record to buf, see Message.
+func (r *Record) appendMessage(buf []byte) []byte {
+	if r.Msg != "" || r.Format == "" && len(r.Values) == 0 {
+		return append(buf, r.Msg...)
+	}
+	if r.Format != "" {
+		return append(buf, fmt.Sprintf(r.Format, r.Values...)...)
+	}
+	return appendValues(buf, r.Values)
+}
+
+// appendValues appends the values formatted as with fmt.Sprint, but with spaces always added between the values,
+// to buf. Values of the basic types are formatted without allocating memory.
+func appendValues(buf []byte, values []any) []byte {
+	for i, v := range values {
+		if i > 0 {
+			buf = append(buf, ' ')
+		}
+		switch v := v.(type) {
+		case string:
+			buf = append(buf, v...)
+		case int:
+			buf = strconv.AppendInt(buf, int64(v), 10)
+		case int8:
+			buf = strconv.AppendInt(buf, int64(v), 10)
+		case int16:
+			buf = strconv.AppendInt(buf, int64(v), 10)
+		case int32:
+			buf = strconv.AppendInt(buf, int64(v), 10)
+		case int64:
+			buf = strconv.AppendInt(buf, v, 10)
+		case uint:
+			buf = strconv.AppendUint(buf, uint64(v), 10)
+		case uint8:
+			buf = strconv.AppendUint(buf, uint64(v), 10)
+		case uint16:
+			buf = strconv.AppendUint(buf, uint64(v), 10)
+		case uint32:
+			buf = strconv.AppendUint(buf, uint64(v), 10)
+		case uint64:
+			buf = strconv.AppendUint(buf, v, 10)
+		case float32:
+			buf = strconv.AppendFloat(buf, float64(v), 'g', -1, 32)
+		case float64:
+			buf = strconv.AppendFloat(buf, v, 'g', -1, 64)
+		case bool:
+			buf = strconv.AppendBool(buf, v)
+		default:
+			buf = append(buf, fmt.Sprint(v)...)
+		}
+	}
+	return buf
+}
+
+// LogValuer is the interface implemented by values which are expensive to compute and should therefore be
+// evaluated lazily. LogValue is called by the log service and only if at least one log destination accepts
+// the log record; its result is logged instead of the LogValuer itself.
+// Besides LogValuer, values of type func() any are evaluated lazily, too.
+type LogValuer interface {
+	LogValue() any
+}
+
+// resolveValues evaluates all lazy values and replaces them by their results.
+// The values are the copy of the values of the caller held by the log message, hence they can be modified.
+func resolveValues(values []any) {
+	for i, v := range values {
+		switch lazy := v.(type) {
+		case LogValuer:
+			values[i] = lazy.LogValue()
+		case func() any:
+			values[i] = lazy()
+		}
+	}
+}
+
+// Formatter is the interface implemented by types that turn a log record into a line of output.
+// A formatter can be set per log destination by calling SetFormatter.
+//
+// Format appends the formatted log record, including the terminating newline, to buf and returns
+// the extended buffer. The buf parameter is the reusable line buffer of the log destination with
+// a length of 0, so formatters don't need to allocate their own buffers.
+// Format is always called from the log service, hence implementations don't need to be safe for
+// concurrent use.
+type Formatter interface {
+	Format(buf []byte, rec *Record) []byte
+}
+
+// TextFormatter formats log records as plain text lines: the prefix of the log destination followed by
+// the component name of the Logger in brackets, if any, the logged values, which are separated by spaces,
+// and the fields formatted as key=value.
+// Date/time placeholders delimited by # tags and the %LEVEL% placeholder in the prefix are replaced by
+// the time and the level of the log record (see SetPrefix).
+// A stack trace attached to the log record is appended as block of lines, indented by a tab, or, with the
+// EscapeLines policy, as escaped stack field of the log record line.
+// If the output is colored, the time is dimmed, the level is colored by its severity and the component
+// name is bold; the message and the fields are never colored.
+// It is the default formatter of all log destinations.
+type TextFormatter struct {
+	Color ColorMode // whether the output is colored; only supported by STDOUT, since ColorAuto checks whether stdout is a terminal
+}
+
+// Format denotes the Formatter interface implementation by the TextFormatter type.
+func (t TextFormatter) Format(buf []byte, rec *Record) []byte {
+	lineStart := len(buf)
+	color := t.Color.enabled()
+	// build log prefix
+	for _, v := range rec.Prefix {
+		if strings.HasPrefix(v, dateTimeTag) && strings.HasSuffix(v, dateTimeTag) {
+			// date/time placeholders found - replace with real date/time values
+			if color {
+				buf = append(buf, ansiDim...)
+			}
+			buf = rec.Time.AppendFormat(buf, strings.Trim(v, dateTimeTag))
+			if color {
+				buf = append(buf, ansiReset...)
+			}
+		} else if i := strings.Index(v, levelTag); i >= 0 {
+			// level placeholder found - replace with the level name
+			buf = append(buf, v[:i]...)
+			if color {
+				buf = append(buf, levelColor(rec.Level)...)
+			}
+			buf = append(buf, rec.Level.String()...)
+			if color {
+				buf = append(buf, ansiReset...)
+			}
+			buf = append(buf, v[i+len(levelTag):]...)
+		} else {
+			// no date/time placeholders found
+			buf = append(buf, v...)
+		}
+		buf = append(buf, ' ')
+	}
+	if rec.Component != "" {
+		if color {
+			buf = append(buf, ansiBold...)
+		}
+		buf = append(buf, '[')
+		buf = append(buf, rec.Component...)
+		buf = append(buf, ']')
+		if color {
+			buf = append(buf, ansiReset...)
+		}
+		buf = append(buf, ' ')
+	}
+	// append payload to the log record
+	start := len(buf)
+	buf = rec.appendSafeMessage(buf, lineStart)
+	for _, f := range rec.Fields {
+		if len(buf) > start {
+			buf = append(buf, ' ')
+		}
+		buf = appendTextField(buf, f)
+	}
+	if rec.Lines == EscapeLines && rec.Stack != "" {
+		// keep the log record a single line by appending the stack trace as escaped field
+		if len(buf) > start {
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, "stack="...)
+		buf = appendLogfmtValue(buf, rec.Stack)
+	}
+	buf = append(buf, '\n')
+	// append stack trace as indented block
+	for stack := rec.Stack; stack != "" && rec.Lines != EscapeLines; {
+		var line string
+		line, stack, _ = strings.Cut(stack, "\n")
+		buf = append(buf, '\t')
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+// JSONFormatter formats log records as JSON objects, one per line (JSON lines).
+// The time is written as "time" in RFC 3339 format with nanoseconds, the level as "level", the component
+// name of the Logger, if any, as "component" and the logged values are written as "msg", followed by the
+// fields. A stack trace attached to the log record is written as "stack". The prefix of the log destination
+// is not used.
+type JSONFormatter struct{}
+
+// Format denotes the Formatter interface implementation by the JSONFormatter type.
+func (JSONFormatter) Format(buf []byte, rec *Record) []byte {
+	buf = append(buf, `{"time":"`...)
+	buf = rec.Time.AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, `","level":"`...)
+	buf = append(buf, rec.Level.String()...)
+	buf = append(buf, '"')
+	if rec.Component != "" {
+		buf = append(buf, `,"component":`...)
+		buf = appendJSONString(buf, rec.Component)
+	}
+	buf = append(buf, `,"msg":`...)
+	buf = appendJSONString(buf, rec.Message())
+	for _, f := range rec.Fields {
+		buf = append(buf, ',')
+		buf = appendJSONField(buf, f)
+	}
+	if rec.Stack != "" {
+		buf = append(buf, `,"stack":`...)
+		buf = appendJSONString(buf, rec.Stack)
+	}
+	return append(buf, "}\n"...)
+}
+
+// LogfmtFormatter formats log records as logfmt lines, i.e. as space separated key=value pairs.
+// The time is written as "time" in RFC 3339 format with nanoseconds, the level as "level", the component
+// name of the Logger, if any, as "component" and the logged values are written as "msg", followed by the
+// fields. A stack trace attached to the log record is written as "stack". The prefix of the log destination
+// is not used.
+type LogfmtFormatter struct{}
+
+// Format denotes the Formatter interface implementation by the LogfmtFormatter type.
+func (LogfmtFormatter) Format(buf []byte, rec *Record) []byte {
+	buf = append(buf, "time="...)
+	buf = rec.Time.AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, " level="...)
+	buf = append(buf, rec.Level.String()...)
+	if rec.Component != "" {
+		buf = append(buf, " component="...)
+		buf = appendLogfmtValue(buf, rec.Component)
+	}
+	buf = append(buf, " msg="...)
+	buf = appendLogfmtValue(buf, rec.Message())
+	for _, f := range rec.Fields {
+		buf = append(buf, ' ')
+		buf = appendTextField(buf, f)
+	}
+	if rec.Stack != "" {
+		buf = append(buf, " stack="...)
+		buf = appendLogfmtValue(buf, rec.Stack)
+	}
+	return append(buf, '\n')
+}
+
+// appendJSONString appends s as quoted and escaped JSON string to buf.
+func appendJSONString(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				buf = append(buf, `�`...)
+			} else {
+				buf = append(buf, s[i:i+size]...)
+			}
+			i += size
+			continue
+		}
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c == '\n':
+			buf = append(buf, '\\', 'n')
+		case c == '\r':
+			buf = append(buf, '\\', 'r')
+		case c == '\t':
+			buf = append(buf, '\\', 't')
+		case c < 0x20 || c == 0x7f:
+			buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+		i++
+	}
+	return append(buf, '"')
+}
+
+// appendLogfmtValue appends s as logfmt value to buf.
+// The value is quoted, if it is empty or contains spaces, quotes, equal signs or control characters.
+func appendLogfmtValue(buf []byte, s string) []byte {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '"' || r == '=' || r == 0x7f || r == utf8.RuneError
+	}) >= 0 {
+		return appendJSONString(buf, s)
+	}
+	return append(buf, s...)
+}
@@ -3,11 +3,15 @@ package simplelog
 import (
 	"bufio"
 	"os"
+	"time"
 )
 
 // general
 const (
-	dateTimeTag = "#"
+	dateTimeTag      = "#"
+	levelTag         = "%LEVEL%"
+	defaultBatchSize = 128         // default maximum number of log messages processed and written at once
+	flushInterval    = time.Second // interval at which log file buffers are flushed and log files are checked
 )
 
 // log destinations
@@ -22,21 +26,53 @@ const (
 	initlog = iota
 	switchlog
 	setprefix
+	closelog
+	setrotation
+	setformatter
+	setlevel
+	applyconfig
+	reopenlog
+	synclog
+	setbatchsize
+	setoverrides
+	getstate
+	rotatelog
+	initring
+	getring
+	setlinepolicy
+	setstripansi
+	setmaxrecordsize
 )
 
 // log service attributes
 const (
-	logbuffer       = iota // defines the buffer size of the logMessage channel
-	logfilename            // defines the log file name to be used
-	logflag                // a flag or a combination of flags which specifies how to open the log file
-	filelogprefix          // defines the prefix that is placed in front of each log line in the log file
-	stdoutlogprefix        // defines the prefix that is placed in front of each log line in stdout
+	logbuffer        = iota // defines the buffer size of the logMessage channel
+	logfilename             // defines the log file name to be used
+	logflag                 // a flag or a combination of flags which specifies how to open the log file
+	filelogprefix           // defines the prefix that is placed in front of each log line in the log file
+	stdoutlogprefix         // defines the prefix that is placed in front of each log line in stdout
+	logdestination          // defines the log destination a config task refers to
+	logarchive              // defines whether a log file is archived when it is closed
+	logmaxsize              // defines the size in bytes at which a log file is rotated
+	logformatter            // defines the formatter used to format log records
+	loglevel                // defines the minimum level of log records written to a log destination
+	logconfig               // defines the configuration to be applied to the log service
+	logdestinations         // defines the log destinations of named log files, keyed by their name
+	logbatchsize            // defines the maximum number of log messages processed and written at once
+	logoverrides            // defines the level overrides by component name or source file
+	logstate                // defines the state of the log service to be filled in by the log service
+	logring                 // defines the ring buffer of a log destination
+	loglinepolicy           // defines how newlines and control characters in messages are written
+	logstripansi            // defines whether ANSI escape sequences are removed from log file records
+	logmaxrecordsize        // defines the size in bytes at which log records are truncated
 )
 
 // a logMessage represents the log message which will be sent to the log service.
+// Log messages are pooled and sent by pointer; the slices of the embedded log record are reused.
 type logMessage struct {
-	destination int   // the log destination bits, e.g. stdout, file, and so on.
-	data        []any // the payload of the log message
+	destination int  // the log destination bits, e.g. stdout, file, and so on.
+	override    bool // true, if the level was accepted by a level override instead of the log destination levels
+	Record           // the log record to be written
 }
 
 // a configMessage represents the object which will be sent to the log service for configuration purposes.
@@ -47,16 +83,28 @@ type configMessage struct {
 
 // stdoutLogger is a data collection to support logging to stdout.
 type stdoutLogger struct {
-	self   *logger
-	prefix []string // prefix for each stdout log record
+	self          *logger
+	prefix        []string   // prefix for each stdout log record
+	formatter     Formatter  // formatter for each stdout log record; nil means TextFormatter
+	level         Level      // minimum level of stdout log records
+	lines         LinePolicy // line policy for each stdout log record
+	maxRecordSize int        // size in bytes at which stdout log records are truncated; 0 means no limit
 }
 
 // fileLogger is a data collection to support logging to files.
 type fileLogger struct {
-	writer *bufio.Writer
-	desc   *os.File
-	self   *logger
-	prefix []string // prefix for each file log record
+	writer        *bufio.Writer
+	desc          *os.File
+	flag          int // flags the log file was opened with
+	self          *logger
+	prefix        []string   // prefix for each file log record
+	formatter     Formatter  // formatter for each file log record; nil means TextFormatter
+	level         Level      // minimum level of file log records
+	size          int64      // number of bytes written to the log file
+	maxSize       int64      // size in bytes at which the log file is rotated; 0 disables rotation
+	lines         LinePolicy // line policy for each file log record
+	stripANSI     bool       // true, if ANSI escape sequences are removed from file log records
+	maxRecordSize int        // size in bytes at which file log records are truncated; 0 means no limit
 }
 
 // logWriter interface includes definitions of the following method signatures:
@@ -0,0 +1,46 @@
+package simplelog
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Level represents the severity of a log record.
+type Level int
+
+// log levels
+const (
+	TRACE Level = iota - 2 // fine-grained information to trace the program flow
+	DEBUG                  // information useful for debugging
+	INFO                   // general information; the level of records written by Write
+	WARN                   // unexpected situations which don't affect the program flow
+	ERROR                  // failures the program has to deal with
+)
+
+// levelNames maps log levels to their names.
+var levelNames = map[Level]string{
+	TRACE: "TRACE",
+	DEBUG: "DEBUG",
+	INFO:  "INFO",
+	WARN:  "WARN",
+	ERROR: "ERROR",
+}
+
+// String returns the name of the log level, e.g. "INFO".
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+}
+
+// ParseLevel returns the log level with the given name.
+// The name is case-insensitive, e.g. "debug" and "DEBUG" both denote DEBUG.
+func ParseLevel(name string) (Level, bool) {
+	for l, n := range levelNames {
+		if strings.EqualFold(n, name) {
+			return l, true
+		}
+	}
+	return INFO, false
+}
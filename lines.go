@@ -0,0 +1,175 @@
+package simplelog
+
+import (
+	"strings"
+)
+
+// LinePolicy denotes how the TextFormatter writes newlines and other control characters contained in the
+// message of a log record. Such characters break line-oriented processing of the log output and allow to
+// inject fake log records or terminal escape sequences by logging user input.
+type LinePolicy int
+
+// line policies
+const (
+	RawLines    LinePolicy = iota // messages are written as they are; this is the default
+	EscapeLines                   // newlines, control characters and backslashes are escaped, e.g. as \n, \x1b and \\, hence each log record is a single line, including a stack trace
+	IndentLines                   // continuation lines are indented under the prefix; other control characters are escaped
+)
+
+// linePolicyNames maps the line policies to their names as used by the configuration.
+var linePolicyNames = map[LinePolicy]string{RawLines: "raw", EscapeLines: "escape", IndentLines: "indent"}
+
+// String returns the name of the line policy, e.g. "escape".
+func (p LinePolicy) String() string {
+	if name, ok := linePolicyNames[p]; ok {
+		return name
+	}
+	return "raw"
+}
+
+// linePolicyByName returns the line policy of a name used in configurations; the empty name denotes RawLines.
+func linePolicyByName(name string) (LinePolicy, bool) {
+	if name == "" {
+		return RawLines, true
+	}
+	for p, n := range linePolicyNames {
+		if strings.EqualFold(n, name) {
+			return p, true
+		}
+	}
+	return RawLines, false
+}
+
+// SetLinePolicy sets how newlines and other control characters in messages are written to a log destination.
+// The line policy is applied by the TextFormatter; the JSONFormatter and the LogfmtFormatter always escape them,
+// as the TextFormatter does for the values of fields.
+// The destination parameter specifies the log destination: STDOUT, FILE or a named log file.
+// The policy parameter specifies the line policy: RawLines, EscapeLines or IndentLines.
+func SetLinePolicy(destination int, policy LinePolicy) {
+	if s.isActive() {
+		if destination != STDOUT && !s.isFileDestination(destination) {
+			panic(sg003)
+		}
+		s.configure(configMessage{setlinepolicy, map[int]any{logdestination: destination, loglinepolicy: policy}})
+	} else {
+		panic(sg002)
+	}
+}
+
+// SetStripANSI sets whether ANSI escape sequences, e.g. colors or cursor movements, are removed from the log
+// records written to a log file. This keeps log files readable and free of escape sequences contained in logged
+// user input or written by formatters which color their output, regardless of the line policy.
+// The destination parameter specifies the log file destination: FILE or a named log file.
+// The strip parameter specifies whether ANSI escape sequences are removed (true) or not (false).
+func SetStripANSI(destination int, strip bool) {
+	if s.isActive() {
+		if !s.isFileDestination(destination) {
+			panic(sg003)
+		}
+		s.configure(configMessage{setstripansi, map[int]any{logdestination: destination, logstripansi: strip}})
+	} else {
+		panic(sg002)
+	}
+}
+
+// appendSafeMessage appends the message of a log record to buf according to its line policy.
+// The lineStart parameter specifies the index in buf at which the line of the log record starts; continuation
+// lines of the IndentLines policy are indented by the width of the line up to the message.
+func (r *Record) appendSafeMessage(buf []byte, lineStart int) []byte {
+	start := len(buf)
+	buf = r.appendMessage(buf)
+	if r.Lines == RawLines || !hasControlChars(buf[start:], r.Lines == EscapeLines) {
+		return buf
+	}
+	msg := string(buf[start:])
+	buf = buf[:start]
+	indent := displayWidth(buf[lineStart:])
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		switch {
+		case c == '\n' && r.Lines == IndentLines:
+			buf = append(buf, '\n')
+			for j := 0; j < indent; j++ {
+				buf = append(buf, ' ')
+			}
+		case c == '\n':
+			buf = append(buf, '\\', 'n')
+		case c == '\r':
+			buf = append(buf, '\\', 'r')
+		case c == '\t':
+			buf = append(buf, c)
+		case c == '\\' && r.Lines == EscapeLines:
+			// escaped, so that an escaped newline can't be confused with a backslash followed by n
+			buf = append(buf, '\\', '\\')
+		case c < 0x20 || c == 0x7f:
+			buf = append(buf, '\\', 'x', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// hasControlChars returns true, if b contains control characters other than tabs or, if backslash is true,
+// backslashes.
+func hasControlChars(b []byte, backslash bool) bool {
+	for _, c := range b {
+		if c < 0x20 && c != '\t' || c == 0x7f || c == '\\' && backslash {
+			return true
+		}
+	}
+	return false
+}
+
+// stripANSI removes ANSI escape sequences from b in place and returns the shortened slice.
+// Removed are control sequences (ESC [ ... final byte), operating system commands (ESC ] ... BEL or ESC \)
+// and other two-byte escape sequences.
+func stripANSI(b []byte) []byte {
+	i := 0
+	for i < len(b) && b[i] != 0x1b {
+		i++
+	}
+	if i == len(b) {
+		return b
+	}
+	out := b[:i]
+	for i < len(b) {
+		if b[i] != 0x1b {
+			out = append(out, b[i])
+			i++
+			continue
+		}
+		i++
+		if i == len(b) {
+			break
+		}
+		switch b[i] {
+		case '[':
+			// control sequence: parameter and intermediate bytes, terminated by a final byte
+			for i++; i < len(b) && b[i] >= 0x20 && b[i] <= 0x3f; i++ {
+			}
+			if i < len(b) && b[i] >= 0x40 && b[i] <= 0x7e {
+				i++
+			}
+		case ']':
+			// operating system command: terminated by BEL or ST (ESC \); an unterminated one ends at the line end
+			for i++; i < len(b) && b[i] != '\n'; i++ {
+				if b[i] == 0x07 {
+					i++
+					break
+				}
+				if b[i] == 0x1b && i+1 < len(b) && b[i+1] == '\\' {
+					i += 2
+					break
+				}
+			}
+		default:
+			// two-byte escape sequence
+			if b[i] >= 0x20 && b[i] <= 0x7e {
+				i++
+			}
+		}
+	}
+	return out
+}
@@ -1,16 +1,28 @@
 package simplelog
 
 import (
-	"fmt"
 	"io"
-	"strings"
-	"time"
+	"strconv"
+	"unicode/utf8"
 )
 
+// maxBatchSize is the size in bytes of a batch of log lines at which it is written, even if there are
+// more log records to be batched.
+const maxBatchSize = 64 * 1024
+
+// maxRetainedBufSize is the capacity in bytes up to which the line buffer and the batch buffer are kept for
+// reuse. Buffers grown beyond it by huge log records are released, so that they don't keep the memory forever.
+const maxRetainedBufSize = 4 * maxBatchSize
+
+// truncationMarker is appended to log records which were truncated, followed by the number of bytes cut off.
+const truncationMarker = "…[truncated "
+
 // logger represents an object that generates lines of output to an io.Writer.
+// Lines are collected into a batch, which is written to the io.Writer at once by writeBatch.
 type logger struct {
 	destination io.Writer // log destination, e.g. stdout or bufio.Writer
 	lineBuf     []byte    // buffer for one line of log data
+	batchBuf    []byte    // buffer for a batch of lines of log data
 }
 
 // newLogger instantiates a new logger.
@@ -19,41 +31,70 @@ func newLogger(destination io.Writer) *logger {
 	return &logger{destination: destination}
 }
 
-// write writes the output for a logging event.
+// write writes the output for a logging event into the current batch.
 // Thereby one logging event corresponds to one line of output at the used log destination.
-func (l *logger) write(logMsg *logMessage) error {
-	var prefix []string
-	l.lineBuf = l.lineBuf[:0] // reset log record
-
-	switch logMsg.destination {
-	case STDOUT:
-		prefix = s.stdoutLogger.prefix
-	case FILE:
-		prefix = s.fileLogger.prefix
-	}
-
-	if len(prefix) > 0 {
-		// build log prefix
-		for _, v := range prefix {
-			if strings.HasPrefix(v, dateTimeTag) && strings.HasSuffix(v, dateTimeTag) {
-				// date/time placeholders found - replace with real date/time values
-				t := time.Now()
-				l.lineBuf = append(l.lineBuf, t.Format(strings.Trim(v, dateTimeTag))...)
-			} else {
-				// no date/time placeholders found
-				l.lineBuf = append(l.lineBuf, v...)
-			}
-			l.lineBuf = append(l.lineBuf, ' ')
-		}
-	}
-
-	// append payload to the log record
-	l.lineBuf = append(l.lineBuf, fmt.Sprintln(logMsg.data...)...)
-	// write log record to the log destination
-	_, err := l.destination.Write(l.lineBuf)
+// The formatter parameter specifies the formatter which turns the log record into a line of output;
+// if it is nil, the TextFormatter is used.
+// The strip parameter specifies whether ANSI escape sequences are removed from the line.
+// The maxSize parameter specifies the size in bytes at which the line is truncated; 0 means no limit.
+// It returns the number of bytes of the line.
+func (l *logger) write(formatter Formatter, rec *Record, strip bool, maxSize int) int {
+	if formatter == nil {
+		formatter = TextFormatter{}
+	}
+	// format log record, reusing the line buffer
+	l.lineBuf = formatter.Format(l.lineBuf[:0], rec)
+	if strip {
+		l.lineBuf = stripANSI(l.lineBuf)
+	}
+	if maxSize > 0 {
+		l.lineBuf = truncateLine(l.lineBuf, maxSize)
+	}
+	// add log record to the batch
+	l.batchBuf = append(l.batchBuf, l.lineBuf...)
+	if len(l.batchBuf) >= maxBatchSize {
+		l.writeBatch()
+	}
+
+	n := len(l.lineBuf)
+	if cap(l.lineBuf) > maxRetainedBufSize {
+		l.lineBuf = nil
+	}
+	return n
+}
+
+// truncateLine truncates a formatted log record to maxSize bytes, not counting the trailing newline, and
+// appends the truncation marker with the number of bytes cut off. The log record is cut at a character boundary.
+// Truncated log records of the JSONFormatter and the LogfmtFormatter are no longer valid JSON or logfmt.
+func truncateLine(line []byte, maxSize int) []byte {
+	end := len(line)
+	if end > 0 && line[end-1] == '\n' {
+		end--
+	}
+	if end <= maxSize {
+		return line
+	}
+	n := maxSize
+	for n > 0 && !utf8.RuneStart(line[n]) {
+		n--
+	}
+	cut := end - n
+	line = append(line[:n], truncationMarker...)
+	line = strconv.AppendInt(line, int64(cut), 10)
+	return append(line, " bytes]\n"...)
+}
+
+// writeBatch writes the current batch of log records to the log destination with a single write.
+func (l *logger) writeBatch() {
+	if len(l.batchBuf) == 0 {
+		return
+	}
+	_, err := l.destination.Write(l.batchBuf)
 	if err != nil {
 		panic(err)
 	}
-
-	return err
+	l.batchBuf = l.batchBuf[:0]
+	if cap(l.batchBuf) > maxRetainedBufSize {
+		l.batchBuf = nil
+	}
 }
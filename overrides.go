@@ -0,0 +1,165 @@
+package simplelog
+
+import (
+	"errors"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// levelOverride represents a rule of a level override specification, see SetLevelOverrides.
+type levelOverride struct {
+	pattern string // the pattern matched against the component name, the source file or the package
+	level   Level  // the minimum level of log records matching the pattern
+}
+
+// levelOverrides represents the active level overrides together with the rules matching the call sites evaluated
+// so far. Whenever the level overrides change, a new instance with an empty cache replaces the old one.
+// The cache is keyed by the program counter only, since the number of call sites is bounded by the program,
+// whereas component names may be built at runtime; component names are matched at each call instead.
+type levelOverrides struct {
+	spec  string          // the specification the level overrides were parsed from
+	rules []levelOverride // the rules in the order of the specification; the first matching rule wins
+	mutex sync.RWMutex    // to serialize the access to the cache
+	sites map[uintptr]int // the index of the first rule matching the source file or package of a call site
+}
+
+// parseLevelOverrides parses a level override specification, which is a comma-separated list of pattern=level
+// pairs, e.g. "http*=debug,db=warn". An empty specification results in no level overrides.
+func parseLevelOverrides(spec string) (*levelOverrides, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	o := &levelOverrides{spec: spec, sites: make(map[uintptr]int)}
+	for _, rule := range strings.Split(spec, ",") {
+		pattern, name, ok := strings.Cut(strings.TrimSpace(rule), "=")
+		if !ok || pattern == "" {
+			return nil, errors.New("level override " + rule + " is not of the form pattern=level")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.New("level override " + rule + " has an invalid pattern")
+		}
+		level, ok := ParseLevel(name)
+		if !ok {
+			return nil, errors.New("level override " + rule + " has an unknown level")
+		}
+		o.rules = append(o.rules, levelOverride{pattern, level})
+	}
+	return o, nil
+}
+
+// level returns the minimum level of log records written at a call site by a logger with the given component name.
+// The ok result is false, if no level override matches the call site.
+// A pattern matches, if it matches the component name, the name of the source file without the .go extension
+// or, if it contains a slash, a trailing part of the path of the source file without the .go extension or
+// of the import path of the package.
+func (o *levelOverrides) level(pc uintptr, component string) (Level, bool) {
+	o.mutex.RLock()
+	first, found := o.sites[pc]
+	o.mutex.RUnlock()
+	if !found {
+		first = o.match(pc)
+		o.mutex.Lock()
+		o.sites[pc] = first
+		o.mutex.Unlock()
+	}
+	if component != "" {
+		// only rules before the rule matching the call site can take precedence
+		for _, rule := range o.rules[:first] {
+			if matchPattern(rule.pattern, component) {
+				return rule.level, true
+			}
+		}
+	}
+	if first < len(o.rules) {
+		return o.rules[first].level, true
+	}
+	return INFO, false
+}
+
+// match returns the index of the first rule matching the source file or the package of a call site or,
+// if no rule matches, the number of rules.
+func (o *levelOverrides) match(pc uintptr) int {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	file := strings.TrimSuffix(frame.File, ".go")
+	pkg := frame.Function
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		if j := strings.Index(pkg[i:], "."); j >= 0 {
+			pkg = pkg[:i+j]
+		}
+	} else if j := strings.Index(pkg, "."); j >= 0 {
+		pkg = pkg[:j]
+	}
+	for i, rule := range o.rules {
+		if !strings.Contains(rule.pattern, "/") {
+			if matchPattern(rule.pattern, path.Base(file)) {
+				return i
+			}
+		} else if matchTrailing(rule.pattern, file) || matchTrailing(rule.pattern, pkg) {
+			return i
+		}
+	}
+	return len(o.rules)
+}
+
+// matchPattern reports whether name matches the pattern, see path.Match.
+func matchPattern(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// matchTrailing reports whether the pattern matches name or a trailing part of name which starts after a slash.
+func matchTrailing(pattern, name string) bool {
+	for {
+		if matchPattern(pattern, name) {
+			return true
+		}
+		i := strings.Index(name, "/")
+		if i < 0 {
+			return false
+		}
+		name = name[i+1:]
+	}
+}
+
+// callSiteLevel returns the level override of the call site of the exported write function which called the
+// calling function, if level overrides are set. The ok result is false, if no level override matches.
+func (s *simpleLogService) callSiteLevel(l *Logger) (Level, bool) {
+	o, _ := s.levelOverrides.Load().(*levelOverrides)
+	if o == nil {
+		return INFO, false
+	}
+	var pc [1]uintptr
+	runtime.Callers(4, pc[:])
+	var component string
+	if l != nil {
+		component = l.component
+	}
+	return o.level(pc[0], component)
+}
+
+// SetLevelOverrides sets level overrides by component name or by source file or package, which take precedence
+// over the levels of the log destinations (see SetLevel), e.g. to debug a single subsystem.
+// The specification is a comma-separated list of pattern=level pairs, like "http*=debug,db=warn". A pattern
+// matches the component name of a Logger (see Named), the name of the source file of the call site without the
+// .go extension or, if the pattern contains a slash, a trailing part of the path of the source file or of the
+// import path of the package, e.g. "internal/db/*". The patterns use the syntax of path.Match and the first
+// matching pattern wins. If a pattern matches, log records with at least the given level are written to all
+// given log destinations, regardless of their levels; log records with a lower level are dropped right at
+// the call site.
+// The source files and packages of the call sites are matched once and are cached until the level overrides
+// change; component names are matched at each call.
+// An empty specification removes all level overrides. An invalid specification is returned as error and
+// keeps the current level overrides.
+func SetLevelOverrides(spec string) error {
+	if s.isActive() {
+		overrides, err := parseLevelOverrides(spec)
+		if err != nil {
+			return err
+		}
+		return s.configure(configMessage{setoverrides, map[int]any{logoverrides: overrides}})
+	} else {
+		panic(sg002)
+	}
+}
@@ -0,0 +1,196 @@
+package simplelog
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// shardedQueue is a multi-producer, single-consumer queue of log messages, which is an alternative to
+// the dataQueue channel for high numbers of concurrent producers.
+//
+// Each shard is a bounded FIFO queue with its own head, tail and mutex. A producer pushes into the shard of
+// a token taken from a sync.Pool, which keeps a token per P, hence producers running on different Ps mostly
+// use different shards and don't contend with each other. The only state all producers share is the epoch,
+// which they read only and which the consumer increments once per batch.
+//
+// Each log message is stamped with the epoch read while its shard is locked. A batch taken by the consumer
+// consists of all log messages of all shards stamped with an epoch before the one the consumer started,
+// which are all visible to the consumer by then. If a producer pushes two log messages into different
+// shards, the first one is stamped with the same or an earlier epoch, hence it is never taken by a later
+// batch than the second one. Within a batch, the log messages are sorted by their time, which preserves
+// the order of the log messages of each producer, as long as the clock advances between them.
+type shardedQueue struct {
+	epoch    uint64        // the epoch log messages are stamped with; accessed atomically
+	capacity uint64        // the number of log messages the queue can hold
+	waiting  int32         // the number of producers waiting by pushCtx for a released slot; accessed atomically
+	next     uint32        // the shard of the next token created by the token pool; accessed atomically
+	shards   []queueShard  // the shards of the queue
+	tokens   sync.Pool     // the tokens of the producers, each holding the index of a shard (*int)
+	ready    chan struct{} // signals the consumer that log messages were pushed
+	released chan struct{} // signals producers waiting by pushCtx that a slot was released
+}
+
+// queueShard is a shard of a shardedQueue, a bounded FIFO queue of log messages.
+// It is padded, so that producers of different shards don't share cache lines.
+type queueShard struct {
+	mutex   sync.Mutex  // to serialize the access to the slots
+	notFull sync.Cond   // to signal producers that a slot was released
+	slots   []queueSlot // the log messages, indexed by their position modulo the number of slots
+	head    uint64      // the position of the next log message to be popped; written with the mutex held, accessed atomically
+	tail    uint64      // the position of the next log message to be pushed; written with the mutex held, accessed atomically
+	_       [64]byte
+}
+
+// queueSlot holds a log message of a queue shard and the epoch it was stamped with.
+type queueSlot struct {
+	logMsg *logMessage
+	epoch  uint64
+}
+
+// newShardedQueue instantiates a new sharded queue.
+// The shards parameter specifies the number of shards and bufferSize the number of log messages which can
+// be buffered; it is rounded up to a multiple of the number of shards.
+func newShardedQueue(shards, bufferSize int) *shardedQueue {
+	if shards < 1 {
+		shards = 1
+	}
+	slots := (bufferSize + shards - 1) / shards
+	if slots < 1 {
+		slots = 1
+	}
+	q := &shardedQueue{
+		capacity: uint64(shards * slots),
+		shards:   make([]queueShard, shards),
+		ready:    make(chan struct{}, 1),
+		released: make(chan struct{}, 1),
+	}
+	q.tokens.New = func() any {
+		shard := int((atomic.AddUint32(&q.next, 1) - 1) % uint32(shards))
+		return &shard
+	}
+	for i := range q.shards {
+		q.shards[i].notFull.L = &q.shards[i].mutex
+		q.shards[i].slots = make([]queueSlot, slots)
+	}
+	return q
+}
+
+// push adds a log message to the queue. If the queue is full, push blocks until the consumer released a slot
+// of the shard of the producer.
+func (q *shardedQueue) push(logMsg *logMessage) {
+	token := q.tokens.Get().(*int)
+	defer q.tokens.Put(token)
+	if q.pushAny(*token, logMsg) {
+		return
+	}
+	shard := &q.shards[*token]
+	shard.mutex.Lock()
+	for shard.tail-shard.head == uint64(len(shard.slots)) {
+		shard.notFull.Wait()
+	}
+	q.store(shard, logMsg)
+}
+
+// pushCtx adds a log message to the queue like push. If the queue is full, pushCtx blocks until either the
+// consumer released a slot or the context is done. It returns false, if the log message
+// wasn't added.
+func (q *shardedQueue) pushCtx(ctx context.Context, logMsg *logMessage) bool {
+	for !q.tryPush(logMsg) {
+		atomic.AddInt32(&q.waiting, 1)
+		if q.tryPush(logMsg) {
+			// a slot was released before the consumer could notice the waiting producer
+			atomic.AddInt32(&q.waiting, -1)
+			return true
+		}
+		select {
+		case <-q.released:
+			atomic.AddInt32(&q.waiting, -1)
+		case <-ctx.Done():
+			atomic.AddInt32(&q.waiting, -1)
+			return false
+		}
+	}
+	return true
+}
+
+// tryPush adds a log message to the queue, if the queue isn't full, and returns true. Otherwise, it returns false.
+func (q *shardedQueue) tryPush(logMsg *logMessage) bool {
+	token := q.tokens.Get().(*int)
+	defer q.tokens.Put(token)
+	return q.pushAny(*token, logMsg)
+}
+
+// pushAny adds a log message to the first shard which isn't full, starting with the shard of the producer,
+// and returns true. It returns false, if all shards are full.
+func (q *shardedQueue) pushAny(first int, logMsg *logMessage) bool {
+	for i := 0; i < len(q.shards); i++ {
+		shard := &q.shards[(first+i)%len(q.shards)]
+		shard.mutex.Lock()
+		if shard.tail-shard.head < uint64(len(shard.slots)) {
+			q.store(shard, logMsg)
+			return true
+		}
+		shard.mutex.Unlock()
+	}
+	return false
+}
+
+// store stores a log message stamped with the current epoch in the next slot of a shard, which must not
+// be full, unlocks the shard and signals the consumer. The mutex of the shard has to be held.
+func (q *shardedQueue) store(shard *queueShard, logMsg *logMessage) {
+	shard.slots[shard.tail%uint64(len(shard.slots))] = queueSlot{logMsg, atomic.LoadUint64(&q.epoch)}
+	atomic.StoreUint64(&shard.tail, shard.tail+1)
+	shard.mutex.Unlock()
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// len returns the number of log messages in the queue.
+func (q *shardedQueue) len() int {
+	n := 0
+	for i := range q.shards {
+		shard := &q.shards[i]
+		n += int(atomic.LoadUint64(&shard.tail) - atomic.LoadUint64(&shard.head))
+	}
+	return n
+}
+
+// popBatch starts a new epoch, removes all log messages stamped with an earlier epoch from the queue and
+// appends them, sorted by their time, to batch. Sorting keeps the order of the log messages of each producer,
+// since their times are increasing, and orders the log messages of different producers by time.
+// popBatch must only be called by the consumer.
+func (q *shardedQueue) popBatch(batch []*logMessage) []*logMessage {
+	start := len(batch)
+	epoch := atomic.AddUint64(&q.epoch, 1) - 1
+	for i := range q.shards {
+		shard := &q.shards[i]
+		shard.mutex.Lock()
+		head := shard.head
+		for ; head < shard.tail; head++ {
+			slot := &shard.slots[head%uint64(len(shard.slots))]
+			if slot.epoch > epoch {
+				break
+			}
+			batch = append(batch, slot.logMsg)
+			*slot = queueSlot{}
+		}
+		if head != shard.head {
+			atomic.StoreUint64(&shard.head, head)
+			shard.notFull.Broadcast()
+		}
+		shard.mutex.Unlock()
+	}
+	if len(batch) > start && atomic.LoadInt32(&q.waiting) > 0 {
+		select {
+		case q.released <- struct{}{}:
+		default:
+		}
+	}
+	popped := batch[start:]
+	sort.SliceStable(popped, func(i, j int) bool { return popped[i].Time.Before(popped[j].Time) })
+	return batch
+}
@@ -0,0 +1,109 @@
+package reader
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// pollInterval is the interval at which a followed log file is checked for new log records and rotation.
+const pollInterval = 250 * time.Millisecond
+
+// follower is an io.Reader which reads a log file like tail -F: at the end of the log file, it waits for new
+// data instead of returning io.EOF. If the log file is rotated, i.e. renamed and created again, or truncated,
+// it continues with the new log file.
+type follower struct {
+	ctx    context.Context // reading stops, when the context is done
+	path   string          // the path of the log file
+	f      *os.File        // the log file being read
+	offset int64           // the number of bytes read from the log file
+}
+
+// Follow returns a Reader which reads the log records written to a log file from now on, like tail -F. It survives
+// the rotation of the log file, i.e. it continues with the new log file once the log file was archived, as well as
+// the truncation of the log file. Next blocks until the next log record is written or the context is done.
+// A log record of the Text format is returned as soon as it was read completely; a stack trace which is written
+// separately is returned as log record of its own.
+// The ctx parameter specifies the context which stops following the log file.
+// The path parameter specifies the log file.
+// The opts parameter specifies the format of the log records.
+func Follow(ctx context.Context, path string, opts Options) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	fw := &follower{ctx: ctx, path: path, f: f, offset: offset}
+	rd := newReader(opts, nil)
+	rd.path, rd.closer, rd.lines, rd.follow = path, fw, bufio.NewReader(fw), true
+	return rd, nil
+}
+
+// Read denotes the io.Reader interface implementation by the follower type.
+func (fw *follower) Read(p []byte) (int, error) {
+	for {
+		n, err := fw.f.Read(p)
+		fw.offset += int64(n)
+		if n > 0 || err != nil && err != io.EOF {
+			return n, err
+		}
+		if rotated, err := fw.reopen(); err != nil {
+			return 0, err
+		} else if rotated {
+			continue
+		}
+		select {
+		case <-fw.ctx.Done():
+			return 0, io.EOF
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// reopen checks whether the log file was rotated or truncated at the end of the log file being read.
+// If the log file was rotated, the remaining data of the old log file is read first. It returns true, if
+// reading should continue at once.
+func (fw *follower) reopen() (bool, error) {
+	st, err := os.Stat(fw.path)
+	if err != nil {
+		// the log file is being rotated; it is created again soon
+		return false, nil
+	}
+	fst, err := fw.f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if !os.SameFile(st, fst) {
+		if fst.Size() > fw.offset {
+			// data written to the old log file before it was archived
+			return true, nil
+		}
+		f, err := os.Open(fw.path)
+		if err != nil {
+			return false, nil
+		}
+		fw.f.Close()
+		fw.f, fw.offset = f, 0
+		return true, nil
+	}
+	if st.Size() < fw.offset {
+		// the log file was truncated
+		if _, err = fw.f.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		fw.offset = 0
+		return true, nil
+	}
+	return false, nil
+}
+
+// Close denotes the io.Closer interface implementation by the follower type.
+func (fw *follower) Close() error {
+	return fw.f.Close()
+}
@@ -0,0 +1,312 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sabitor/simplelog"
+)
+
+// prefix element kinds
+const (
+	literalElement = iota // an element written as is
+	timeElement           // a #layout# element, which is replaced by the time of the log record
+	levelElement          // an element containing %LEVEL%, which is replaced by the level of the log record
+)
+
+// tags of the prefix, see simplelog.SetPrefix
+const (
+	dateTimeTag = "#"
+	levelTag    = "%LEVEL%"
+)
+
+// prefixElement represents an element of the prefix of the Text format.
+type prefixElement struct {
+	kind   int    // the kind of the element
+	text   string // the text of a literal element, the layout of a time element or the text before %LEVEL%
+	suffix string // the text after %LEVEL% of a level element
+}
+
+// errors reported by ParseError
+var (
+	errPrefix = errors.New("line doesn't match the prefix")
+	errFields = errors.New("line isn't a list of key=value pairs")
+	errJSON   = errors.New("line isn't a JSON object")
+)
+
+// parsePrefix parses the prefix of a log destination the same way the TextFormatter interprets it.
+func parsePrefix(prefix []string) []prefixElement {
+	elements := make([]prefixElement, 0, len(prefix))
+	for _, v := range prefix {
+		if strings.HasPrefix(v, dateTimeTag) && strings.HasSuffix(v, dateTimeTag) {
+			elements = append(elements, prefixElement{kind: timeElement, text: strings.Trim(v, dateTimeTag)})
+		} else if i := strings.Index(v, levelTag); i >= 0 {
+			elements = append(elements, prefixElement{kind: levelElement, text: v[:i], suffix: v[i+len(levelTag):]})
+		} else {
+			elements = append(elements, prefixElement{kind: literalElement, text: v})
+		}
+	}
+	return elements
+}
+
+// parseText parses a line written by the TextFormatter with the given prefix.
+// The prefix is followed by the component in brackets, if any, and by the message. The longest tail of the
+// message which consists of key=value pairs only is taken as fields; their values are returned as strings.
+// Hence, a message which ends with key=value pairs itself or starts with a word in brackets can't be told
+// apart from fields or a component.
+func parseText(line string, prefix []prefixElement, loc *time.Location) (simplelog.Record, error) {
+	var rec simplelog.Record
+	rest := line
+	for _, e := range prefix {
+		switch e.kind {
+		case timeElement:
+			// the formatted time may contain spaces; the first candidate which can be parsed is taken
+			end := -1
+			for i := 0; i < len(rest); i++ {
+				if rest[i] != ' ' {
+					continue
+				}
+				if t, err := time.ParseInLocation(e.text, rest[:i], loc); err == nil {
+					rec.Time, end = t, i
+					break
+				}
+			}
+			if end < 0 {
+				return rec, errPrefix
+			}
+			rest = rest[end:]
+		case levelElement:
+			if !strings.HasPrefix(rest, e.text) {
+				return rec, errPrefix
+			}
+			rest = rest[len(e.text):]
+			end := strings.Index(rest, e.suffix+" ")
+			if end < 0 {
+				return rec, errPrefix
+			}
+			level, ok := parseLevel(rest[:end])
+			if !ok {
+				return rec, errPrefix
+			}
+			rec.Level = level
+			rest = rest[end+len(e.suffix):]
+		default:
+			if !strings.HasPrefix(rest, e.text) {
+				return rec, errPrefix
+			}
+			rest = rest[len(e.text):]
+		}
+		if !strings.HasPrefix(rest, " ") {
+			return rec, errPrefix
+		}
+		rest = rest[1:]
+	}
+	if strings.HasPrefix(rest, "[") {
+		if i := strings.Index(rest, "] "); i > 1 && !strings.ContainsAny(rest[1:i], " []") {
+			rec.Component, rest = rest[1:i], rest[i+2:]
+		}
+	}
+	// the fields start at the first word boundary from which on the rest consists of key=value pairs only
+	for i := 0; i <= len(rest); i++ {
+		if i > 0 && i < len(rest) && rest[i-1] != ' ' {
+			continue
+		}
+		if fields, ok := parseFields(rest[i:]); ok {
+			rec.Msg, rec.Fields = strings.TrimSuffix(rest[:i], " "), fields
+			break
+		}
+	}
+	return rec, nil
+}
+
+// parseFields parses space separated key=value pairs into string fields. Quoted values are unquoted.
+// It returns false, if s doesn't consist of key=value pairs only.
+func parseFields(s string) ([]simplelog.Field, bool) {
+	var fields []simplelog.Field
+	err := scanPairs(s, func(key, value string) {
+		fields = append(fields, simplelog.String(key, value))
+	})
+	return fields, err == nil
+}
+
+// scanPairs calls fn for all space separated key=value pairs of s, in order. Quoted values are unquoted.
+// It returns an error, if s doesn't consist of key=value pairs only.
+func scanPairs(s string, fn func(key, value string)) error {
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 1 || strings.ContainsAny(s[:eq], " \"") {
+			return errFields
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := quotedEnd(s)
+			if end < 0 {
+				return errFields
+			}
+			var err error
+			if value, err = strconv.Unquote(s[:end]); err != nil {
+				return errFields
+			}
+			s = s[end:]
+		} else {
+			end := strings.IndexByte(s, ' ')
+			if end < 0 {
+				end = len(s)
+			}
+			if strings.ContainsAny(s[:end], "\"=") {
+				return errFields
+			}
+			value, s = s[:end], s[end:]
+		}
+		if s != "" {
+			if s[0] != ' ' || len(s) == 1 {
+				return errFields
+			}
+			s = s[1:]
+		}
+		fn(key, value)
+	}
+	return nil
+}
+
+// quotedEnd returns the index after the closing quote of the quoted string s starts with, or -1.
+func quotedEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// parseLogfmt parses a line written by the LogfmtFormatter. The values of the fields are returned as strings.
+func parseLogfmt(line string) (simplelog.Record, error) {
+	var rec simplelog.Record
+	var err error
+	scanErr := scanPairs(line, func(key, value string) {
+		if err != nil {
+			return
+		}
+		switch key {
+		case "time":
+			rec.Time, err = time.Parse(time.RFC3339Nano, value)
+		case "level":
+			var ok bool
+			if rec.Level, ok = parseLevel(value); !ok {
+				err = errors.New("unknown log level " + strconv.Quote(value))
+			}
+		case "component":
+			rec.Component = value
+		case "msg":
+			rec.Msg = value
+		case "stack":
+			rec.Stack = value
+		default:
+			rec.Fields = append(rec.Fields, simplelog.String(key, value))
+		}
+	})
+	if scanErr != nil {
+		return rec, scanErr
+	}
+	return rec, err
+}
+
+// parseJSON parses a line written by the JSONFormatter. The fields keep their order; strings are returned as
+// strings, numbers as int64, uint64 or float64, bools as bools and all other values as Any fields.
+func parseJSON(line string) (simplelog.Record, error) {
+	var rec simplelog.Record
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return rec, errJSON
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return rec, errJSON
+		}
+		key, _ := t.(string)
+		var raw json.RawMessage
+		if err = dec.Decode(&raw); err != nil {
+			return rec, errJSON
+		}
+		switch key {
+		case "time", "level", "component", "msg", "stack":
+			var value string
+			if err = json.Unmarshal(raw, &value); err != nil {
+				return rec, errors.New(key + " isn't a JSON string")
+			}
+			switch key {
+			case "time":
+				if rec.Time, err = time.Parse(time.RFC3339Nano, value); err != nil {
+					return rec, err
+				}
+			case "level":
+				var ok bool
+				if rec.Level, ok = parseLevel(value); !ok {
+					return rec, errors.New("unknown log level " + strconv.Quote(value))
+				}
+			case "component":
+				rec.Component = value
+			case "msg":
+				rec.Msg = value
+			case "stack":
+				rec.Stack = value
+			}
+		default:
+			rec.Fields = append(rec.Fields, jsonField(key, raw))
+		}
+	}
+	if t, err := dec.Token(); err != nil || t != json.Delim('}') {
+		return rec, errJSON
+	}
+	return rec, nil
+}
+
+// jsonField returns the field of a JSON value.
+func jsonField(key string, raw json.RawMessage) simplelog.Field {
+	raw = bytes.TrimSpace(raw)
+	switch raw[0] {
+	case '"':
+		var s string
+		json.Unmarshal(raw, &s)
+		return simplelog.String(key, s)
+	case 't', 'f':
+		return simplelog.Bool(key, raw[0] == 't')
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		if i, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			return simplelog.Int64(key, i)
+		}
+		if u, err := strconv.ParseUint(string(raw), 10, 64); err == nil {
+			return simplelog.Uint64(key, u)
+		}
+		if f, err := strconv.ParseFloat(string(raw), 64); err == nil {
+			return simplelog.Float64(key, f)
+		}
+	}
+	var v any
+	json.Unmarshal(raw, &v)
+	return simplelog.Any(key, v)
+}
+
+// parseLevel returns the log level with the given name, including the names of unnamed levels like LEVEL(5).
+func parseLevel(name string) (simplelog.Level, bool) {
+	if level, ok := simplelog.ParseLevel(name); ok {
+		return level, true
+	}
+	if strings.HasPrefix(name, "LEVEL(") && strings.HasSuffix(name, ")") {
+		if n, err := strconv.Atoi(name[len("LEVEL(") : len(name)-1]); err == nil {
+			return simplelog.Level(n), true
+		}
+	}
+	return simplelog.INFO, false
+}
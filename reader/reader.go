@@ -0,0 +1,362 @@
+// Package reader reads log files written by simplelog back into log records.
+// It parses the formats of the formatters shipped with simplelog: text lines of the TextFormatter, given the
+// prefix the log destination was configured with, JSON lines of the JSONFormatter and logfmt lines of the
+// LogfmtFormatter. A Reader iterates over a single log file, a gzip compressed log file or a whole set of
+// rotated log files in the order they were written.
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sabitor/simplelog"
+)
+
+// Format denotes the format of a log file.
+type Format int
+
+// log file formats
+const (
+	Text   Format = iota // lines written by the TextFormatter
+	JSON                 // lines written by the JSONFormatter
+	Logfmt               // lines written by the LogfmtFormatter
+)
+
+// Options represents the options of a Reader.
+type Options struct {
+	Format   Format         // the format of the log file
+	Prefix   []string       // the prefix of the log destination (see simplelog.SetPrefix); used by the Text format only
+	Location *time.Location // the location of times without time zone in the prefix; nil means time.Local
+	Strict   bool           // if true, lines which can't be parsed are reported as error, see Reader.Err
+}
+
+// ParseError represents a line of a log file which can't be parsed.
+type ParseError struct {
+	Path string // the path of the log file; empty, if the Reader doesn't read from a file
+	Line int    // the number of the line, starting with 1
+	Err  error  // the reason why the line can't be parsed
+}
+
+// Error denotes the error interface implementation by the ParseError type.
+func (e *ParseError) Error() string {
+	return e.Path + ":" + strconv.Itoa(e.Line) + ": " + e.Err.Error()
+}
+
+// Unwrap returns the reason why the line can't be parsed.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads log records from one or more log files. It is used like a bufio.Scanner:
+//
+//	r, err := reader.Open("app.log", reader.Options{Prefix: []string{"#2006-01-02 15:04:05.000000#", "[%LEVEL%]"}})
+//	if err != nil {
+//		...
+//	}
+//	defer r.Close()
+//	for r.Next() {
+//		rec := r.Record()
+//		...
+//	}
+//	if err := r.Err(); err != nil {
+//		...
+//	}
+type Reader struct {
+	opts    Options           // the options of the reader
+	prefix  []prefixElement   // the parsed prefix of the Text format
+	paths   []string          // the log files still to be read
+	path    string            // the log file being read
+	closer  io.Closer         // closes the log file being read; nil if the reader doesn't read from a file
+	lines   *bufio.Reader     // the lines of the log file being read
+	line    int               // the number of the last line read
+	rec     simplelog.Record  // the current log record
+	pending *simplelog.Record // the log record read ahead by the Text format, which may be continued by a stack trace
+	follow  bool              // true, if the reader follows a log file, see Follow
+	file    *os.File          // the log file being read, if it can be searched by SeekTime; otherwise nil
+	from    time.Time         // log records before this time are skipped, see SeekTime
+	err     error             // the first error which occurred
+}
+
+// NewReader returns a Reader which reads log records from r.
+// The opts parameter specifies the format of the log records.
+func NewReader(r io.Reader, opts Options) *Reader {
+	rd := newReader(opts, nil)
+	rd.lines = bufio.NewReader(r)
+	return rd
+}
+
+// Open returns a Reader which reads log records from a log file. Log files compressed by gzip are
+// decompressed transparently.
+// The path parameter specifies the log file.
+// The opts parameter specifies the format of the log records.
+func Open(path string, opts Options) (*Reader, error) {
+	rd := newReader(opts, []string{path})
+	if err := rd.openNext(); err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
+
+// OpenRotated returns a Reader which reads the log records of a log file and of all its archives, i.e. the log
+// files which were archived by rotation or at shutdown (<path>_yyyymmddHHMMSS, optionally followed by a
+// sequence number and the .gz extension, if they were compressed afterwards). The archives are read in the
+// order they were archived, the log file itself last. If neither the log file nor any archive exists, an error
+// is returned.
+// The path parameter specifies the log file.
+// The opts parameter specifies the format of the log records.
+func OpenRotated(path string, opts Options) (*Reader, error) {
+	paths, err := RotatedFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	rd := newReader(opts, paths)
+	if err = rd.openNext(); err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
+
+// RotatedFiles returns the archives of a log file in the order they were archived, followed by the log file
+// itself, if it exists. See OpenRotated.
+// The path parameter specifies the log file.
+func RotatedFiles(path string) ([]string, error) {
+	matches, err := filepath.Glob(globEscape(path) + "_[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+	type archive struct {
+		path      string
+		timestamp string
+		seq       int
+	}
+	var archives []archive
+	for _, match := range matches {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(match, path+"_"), ".gz")
+		timestamp, seq, _ := strings.Cut(suffix, ".")
+		if len(timestamp) != len("yyyymmddHHMMSS") || strings.Trim(timestamp, "0123456789") != "" {
+			continue
+		}
+		n := 0
+		if seq != "" {
+			if n, err = strconv.Atoi(seq); err != nil {
+				continue
+			}
+		}
+		archives = append(archives, archive{match, timestamp, n})
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		if archives[i].timestamp != archives[j].timestamp {
+			return archives[i].timestamp < archives[j].timestamp
+		}
+		return archives[i].seq < archives[j].seq
+	})
+	paths := make([]string, 0, len(archives)+1)
+	for _, a := range archives {
+		paths = append(paths, a.path)
+	}
+	if _, err = os.Stat(path); err == nil {
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// globEscape escapes the meta characters of filepath.Match in a path.
+func globEscape(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[\`, r) && filepath.Separator != '\\' {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// newReader instantiates a new Reader which reads the given log files.
+func newReader(opts Options, paths []string) *Reader {
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	return &Reader{opts: opts, prefix: parsePrefix(opts.Prefix), paths: paths}
+}
+
+// openNext closes the log file being read and opens the next one.
+// If there is no next log file, lines is set to nil.
+func (r *Reader) openNext() error {
+	if r.closer != nil {
+		r.closer.Close()
+		r.closer = nil
+	}
+	r.lines, r.file = nil, nil
+	if len(r.paths) == 0 {
+		return nil
+	}
+	r.path, r.paths, r.line = r.paths[0], r.paths[1:], 0
+	f, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	lines := bufio.NewReader(f)
+	if magic, _ := lines.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(lines)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		r.closer = multiCloser{gz, f}
+		r.lines = bufio.NewReader(gz)
+	} else {
+		r.closer, r.file = f, f
+		r.lines = lines
+	}
+	return nil
+}
+
+// multiCloser closes a gzip reader together with the underlying file.
+type multiCloser struct {
+	gz *gzip.Reader
+	f  *os.File
+}
+
+// Close denotes the io.Closer interface implementation by the multiCloser type.
+func (c multiCloser) Close() error {
+	c.gz.Close()
+	return c.f.Close()
+}
+
+// Next advances the Reader to the next log record, which is then available by Record.
+// It returns false when there are no more log records or an error occurred, see Err.
+func (r *Reader) Next() bool {
+	for r.next() {
+		if !r.from.IsZero() {
+			if r.rec.Time.Before(r.from) {
+				continue
+			}
+			// the log records are ordered by time; all following log records are passed
+			r.from = time.Time{}
+		}
+		return true
+	}
+	return false
+}
+
+// next advances the Reader to the next log record like Next, but regardless of SeekTime.
+func (r *Reader) next() bool {
+	for r.err == nil {
+		if r.follow && r.pending != nil && r.lines != nil && r.lines.Buffered() == 0 {
+			// don't wait for the next log record, which may be written much later
+			r.rec, r.pending = *r.pending, nil
+			return true
+		}
+		line, err := r.readLine()
+		if err != nil {
+			if err != io.EOF {
+				r.err = err
+			} else if r.pending != nil {
+				r.rec, r.pending = *r.pending, nil
+				return true
+			}
+			return false
+		}
+		if r.opts.Format == Text && strings.HasPrefix(line, "\t") && r.pending != nil {
+			// continuation of the stack trace of the pending log record
+			r.pending.Stack += line[1:] + "\n"
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		rec, err := r.parse(line)
+		if err != nil {
+			if r.opts.Strict {
+				r.err = &ParseError{Path: r.path, Line: r.line, Err: err}
+				return false
+			}
+			rec = simplelog.Record{Msg: line}
+		}
+		if r.opts.Format != Text {
+			r.rec = rec
+			return true
+		}
+		pending := r.pending
+		r.pending = &rec
+		if pending != nil {
+			r.rec = *pending
+			return true
+		}
+	}
+	return false
+}
+
+// readLine returns the next line without the trailing newline, continuing with the next log file at the end of
+// a log file. At the end of the last log file, io.EOF is returned.
+func (r *Reader) readLine() (string, error) {
+	for r.lines != nil {
+		line, err := r.lines.ReadString('\n')
+		if line != "" {
+			r.line++
+			return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+		}
+		if err != io.EOF {
+			return "", err
+		}
+		if len(r.paths) == 0 && r.closer == nil {
+			// reading from an io.Reader
+			r.lines = nil
+			break
+		}
+		if err = r.openNext(); err != nil {
+			return "", err
+		}
+	}
+	return "", io.EOF
+}
+
+// parse parses a line according to the format of the Reader.
+func (r *Reader) parse(line string) (simplelog.Record, error) {
+	switch r.opts.Format {
+	case JSON:
+		return parseJSON(line)
+	case Logfmt:
+		return parseLogfmt(line)
+	}
+	return parseText(line, r.prefix, r.opts.Location)
+}
+
+// Record returns the current log record. The stack trace of a log record written by the TextFormatter
+// has no indentation.
+func (r *Reader) Record() simplelog.Record {
+	return r.rec
+}
+
+// Path returns the path of the log file the current log record was read from.
+func (r *Reader) Path() string {
+	return r.path
+}
+
+// Err returns the first error which occurred while reading the log records, if any.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Close closes the log file being read.
+func (r *Reader) Close() error {
+	r.paths = nil
+	if r.closer == nil {
+		return nil
+	}
+	err := r.closer.Close()
+	r.closer, r.lines = nil, nil
+	return err
+}
@@ -0,0 +1,233 @@
+package reader
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sabitor/simplelog"
+)
+
+var testPrefix = []string{"#2006-01-02 15:04:05.000000#", "[%LEVEL%]", "app"}
+
+func testRecords() []simplelog.Record {
+	t0 := time.Date(2024, 5, 17, 10, 30, 0, 123456000, time.UTC)
+	return []simplelog.Record{
+		{Time: t0, Level: simplelog.INFO, Msg: "server started", Fields: []simplelog.Field{simplelog.String("addr", ":8080"), simplelog.String("mode", "a b=c")}},
+		{Time: t0.Add(time.Second), Level: simplelog.WARN, Component: "http.client", Msg: "retrying request"},
+		{Time: t0.Add(2 * time.Second), Level: simplelog.ERROR, Msg: "request failed", Fields: []simplelog.Field{simplelog.String("err", "timeout")}, Stack: "main.main()\n\tmain.go:12\n"},
+		{Time: t0.Add(3 * time.Second), Level: simplelog.Level(5), Fields: []simplelog.Field{simplelog.String("only", "fields")}},
+	}
+}
+
+func format(formatter simplelog.Formatter, prefix []string, records []simplelog.Record) string {
+	var buf []byte
+	for i := range records {
+		rec := records[i]
+		rec.Prefix = prefix
+		buf = formatter.Format(buf, &rec)
+	}
+	return string(buf)
+}
+
+func readAll(t *testing.T, r *Reader) []simplelog.Record {
+	t.Helper()
+	var records []simplelog.Record
+	for r.Next() {
+		records = append(records, r.Record())
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal("Expected no error but got", err)
+	}
+	return records
+}
+
+func TestReadFormats(t *testing.T) {
+	want := testRecords()
+	tests := []struct {
+		name      string
+		formatter simplelog.Formatter
+		opts      Options
+	}{
+		{"text", simplelog.TextFormatter{}, Options{Format: Text, Prefix: testPrefix, Location: time.UTC, Strict: true}},
+		{"json", simplelog.JSONFormatter{}, Options{Format: JSON, Strict: true}},
+		{"logfmt", simplelog.LogfmtFormatter{}, Options{Format: Logfmt, Strict: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// log files start with an empty line
+			input := "\n" + format(tt.formatter, testPrefix, want)
+			got := readAll(t, NewReader(strings.NewReader(input), tt.opts))
+			if len(got) != len(want) {
+				t.Fatalf("Expected %d records but got %d", len(want), len(got))
+			}
+			for i := range want {
+				if !got[i].Time.Equal(want[i].Time) || got[i].Level != want[i].Level || got[i].Component != want[i].Component ||
+					got[i].Msg != want[i].Msg || got[i].Stack != want[i].Stack || !reflect.DeepEqual(got[i].Fields, want[i].Fields) {
+					t.Errorf("Expected record %d %+v but got %+v", i, want[i], got[i])
+				}
+			}
+		})
+	}
+
+	// typed JSON fields
+	line := `{"time":"2024-05-17T10:30:00Z","level":"DEBUG","msg":"x","n":-3,"u":18446744073709551615,"f":1.5,"ok":true,"v":[1]}` + "\n"
+	got := readAll(t, NewReader(strings.NewReader(line), Options{Format: JSON}))
+	fields := []simplelog.Field{simplelog.Int64("n", -3), simplelog.Uint64("u", 18446744073709551615), simplelog.Float64("f", 1.5),
+		simplelog.Bool("ok", true), simplelog.Any("v", []any{1.0})}
+	if len(got) != 1 || got[0].Level != simplelog.DEBUG || !reflect.DeepEqual(got[0].Fields, fields) {
+		t.Errorf("Expected fields %v but got %+v", fields, got)
+	}
+
+	// lenient and strict mode
+	input := "garbage line\n" + format(simplelog.TextFormatter{}, testPrefix, want[:1])
+	got = readAll(t, NewReader(strings.NewReader(input), Options{Prefix: testPrefix}))
+	if len(got) != 2 || got[0].Msg != "garbage line" || got[1].Msg != "server started" {
+		t.Errorf("Expected the garbage line as message but got %+v", got)
+	}
+	r := NewReader(strings.NewReader(input), Options{Prefix: testPrefix, Strict: true})
+	if r.Next() {
+		t.Error("Expected no record in strict mode")
+	}
+	if err, ok := r.Err().(*ParseError); !ok || err.Line != 1 {
+		t.Error("Expected a parse error of line 1 but got", r.Err())
+	}
+}
+
+func TestOpenRotated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	records := testRecords()
+	write := func(name string, rec simplelog.Record, compress bool) {
+		data := format(simplelog.JSONFormatter{}, nil, []simplelog.Record{rec})
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if compress {
+			gz := gzip.NewWriter(f)
+			gz.Write([]byte(data))
+			gz.Close()
+		} else {
+			f.WriteString(data)
+		}
+	}
+	write("app.log", records[3], false)
+	write("app.log_20240517103001.1", records[2], false)
+	write("app.log_20240517103001.gz", records[1], true)
+	write("app.log_20240517103000", records[0], false)
+	write("app.log_other", records[0], false)
+
+	r, err := OpenRotated(path, Options{Format: JSON, Strict: true})
+	if err != nil {
+		t.Fatal("Expected no error but got", err)
+	}
+	defer r.Close()
+	got := readAll(t, r)
+	if len(got) != len(records) {
+		t.Fatalf("Expected %d records but got %d", len(records), len(got))
+	}
+	for i := range records {
+		if !got[i].Time.Equal(records[i].Time) {
+			t.Errorf("Expected record %d of %v but got %v", i, records[i].Time, got[i].Time)
+		}
+	}
+
+	if _, err = OpenRotated(filepath.Join(dir, "missing.log"), Options{}); !os.IsNotExist(err) {
+		t.Error("Expected a not exist error but got", err)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("INFO old record\n"), 0644)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, err := Follow(ctx, path, Options{Prefix: []string{"%LEVEL%"}, Strict: true})
+	if err != nil {
+		t.Fatal("Expected no error but got", err)
+	}
+	defer r.Close()
+
+	appendLine := func(line string) {
+		f, _ := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		f.WriteString(line)
+		f.Close()
+	}
+	go func() {
+		appendLine("INFO first record\n")
+		time.Sleep(2 * pollInterval)
+		appendLine("WARN last record before rotation\n")
+		os.Rename(path, path+"_20240517103000")
+		appendLine("ERROR first record after rotation\n")
+	}()
+	want := []string{"first record", "last record before rotation", "first record after rotation"}
+	for _, msg := range want {
+		if !r.Next() {
+			t.Fatal("Expected a record but got", r.Err())
+		}
+		if r.Record().Msg != msg {
+			t.Errorf("Expected message %q but got %q", msg, r.Record().Msg)
+		}
+	}
+	cancel()
+	if r.Next() {
+		t.Error("Expected no record after the context is done but got", r.Record())
+	}
+}
+
+func TestSeekTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	t0 := time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC)
+	var records []simplelog.Record
+	for i := 0; i < 20000; i++ {
+		rec := simplelog.Record{Time: t0.Add(time.Duration(i) * time.Second), Level: simplelog.INFO, Msg: "request", Fields: []simplelog.Field{simplelog.Int("n", i)}}
+		if i%100 == 0 {
+			rec.Stack = "main.main()\n\tmain.go:12\n"
+		}
+		records = append(records, rec)
+	}
+	os.WriteFile(path, []byte(format(simplelog.TextFormatter{}, testPrefix, records)), 0644)
+
+	for _, n := range []int{0, 1, 12345, 15000, 19999} {
+		r, err := Open(path, Options{Prefix: testPrefix, Location: time.UTC, Strict: true})
+		if err != nil {
+			t.Fatal("Expected no error but got", err)
+		}
+		if err = r.SeekTime(records[n].Time); err != nil {
+			t.Fatal("Expected no error but got", err)
+		}
+		if !r.Next() || !r.Record().Time.Equal(records[n].Time) || r.Record().Stack != records[n].Stack {
+			t.Errorf("Expected record %d but got %+v (%v)", n, r.Record(), r.Err())
+		}
+		if n > 0 && r.line > 2000 {
+			t.Errorf("Expected the seek to skip most lines but %d lines were read", r.line)
+		}
+		r.Close()
+	}
+
+	// archives which end before the time are skipped
+	os.Rename(path, path+"_20240517053320")
+	os.WriteFile(path, []byte(format(simplelog.TextFormatter{}, testPrefix, []simplelog.Record{{Time: t0.Add(time.Hour * 6), Msg: "current"}})), 0644)
+	r, err := OpenRotated(path, Options{Prefix: testPrefix, Location: time.UTC, Strict: true})
+	if err != nil {
+		t.Fatal("Expected no error but got", err)
+	}
+	defer r.Close()
+	r.SeekTime(t0.Add(time.Hour * 5))
+	if got := readAll(t, r); len(got) != 2001 || !got[0].Time.Equal(t0.Add(time.Hour*5)) || got[2000].Msg != "current" {
+		t.Errorf("Expected 2001 records from %v but got %d", t0.Add(time.Hour*5), len(got))
+	}
+	r, _ = OpenRotated(path, Options{Prefix: testPrefix, Location: time.UTC})
+	defer r.Close()
+	r.SeekTime(t0.Add(time.Hour * 7))
+	if r.Path() != path || r.Next() {
+		t.Error("Expected no records after the last archive to be read but got", r.Record())
+	}
+}
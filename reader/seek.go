@@ -0,0 +1,109 @@
+package reader
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// seekWindow is the size of the range of a log file which is read linearly after the binary search by SeekTime.
+const seekWindow = 64 << 10
+
+// SeekTime advances the Reader to the first log record at or after a given time, assuming that the log records
+// are ordered by time, which is the case for log files written by simplelog. Within an uncompressed log file,
+// the log record is searched by binary search on byte offsets, i.e. only a few small parts of the log file are
+// read, regardless of its size. The archives of a set of rotated log files which end before the time are skipped
+// after reading their first log record only. Compressed archives are read up to the log record.
+// After SeekTime, the line numbers reported by ParseError count from the position found.
+// The t parameter specifies the time.
+func (r *Reader) SeekTime(t time.Time) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.from, r.pending = t, nil
+	// the log records of the log file being read end before the first log record of the next log file
+	for len(r.paths) > 0 {
+		next, ok := firstTime(r.paths[0], r.opts)
+		if !ok || !next.Before(t) {
+			break
+		}
+		if r.err = r.openNext(); r.err != nil {
+			return r.err
+		}
+	}
+	if r.file == nil {
+		// the log records before the time are skipped by Next
+		return nil
+	}
+	st, err := r.file.Stat()
+	if err != nil {
+		r.err = err
+		return err
+	}
+	lo, hi := int64(0), st.Size()
+	for hi-lo > seekWindow {
+		mid := lo + (hi-lo)/2
+		_, rt, ok := r.syncRecord(mid, hi)
+		if ok && rt.Before(t) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	start := int64(0)
+	if lo > 0 {
+		start, _, _ = r.syncRecord(lo, st.Size())
+	}
+	if _, err = r.file.Seek(start, io.SeekStart); err != nil {
+		r.err = err
+		return err
+	}
+	r.lines.Reset(r.file)
+	r.line = 0
+	return nil
+}
+
+// syncRecord returns the offset and the time of the first log record which starts in the log file being read
+// after the line containing the offset off and before the offset end. It returns false, if there is none.
+func (r *Reader) syncRecord(off, end int64) (int64, time.Time, bool) {
+	lines := bufio.NewReader(io.NewSectionReader(r.file, off, 1<<62))
+	// skip the rest of the line containing off
+	skipped, err := lines.ReadString('\n')
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	pos := off + int64(len(skipped))
+	for pos < end {
+		line, err := lines.ReadString('\n')
+		if line == "" && err != nil {
+			break
+		}
+		start := pos
+		pos += int64(len(line))
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if strings.TrimSpace(line) == "" || r.opts.Format == Text && strings.HasPrefix(line, "\t") {
+			continue
+		}
+		if rec, err := r.parse(line); err == nil && !rec.Time.IsZero() {
+			return start, rec.Time, true
+		}
+	}
+	return 0, time.Time{}, false
+}
+
+// firstTime returns the time of the first log record of a log file.
+func firstTime(path string, opts Options) (time.Time, bool) {
+	opts.Strict = false
+	r, err := Open(path, opts)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer r.Close()
+	for r.next() {
+		if t := r.Record().Time; !t.IsZero() {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
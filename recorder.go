@@ -0,0 +1,118 @@
+package simplelog
+
+import (
+	"context"
+	"sync"
+)
+
+// recorderKey is the key of the flight recorder stored in a context by NewRecorderContext.
+type recorderKey struct{}
+
+// recorder represents a flight recorder, which keeps the most recent DEBUG and TRACE log messages of a logger
+// or a context instead of sending them to the log service. The log messages kept are sent to the log service,
+// once an ERROR log message is written or a dump is requested.
+type recorder struct {
+	mutex    sync.Mutex    // to serialize the access to the log messages
+	messages []*logMessage // the log messages kept, the oldest one at index head
+	head     int           // the index of the oldest log message
+	count    int           // the number of log messages kept
+}
+
+// newRecorder instantiates a new flight recorder, which keeps up to size log messages.
+func newRecorder(size int) *recorder {
+	if size < 1 {
+		size = 1
+	}
+	return &recorder{messages: make([]*logMessage, size)}
+}
+
+// recorderOf returns the flight recorder of a logger or, if the logger has none, of a context.
+// It returns nil, if neither has a flight recorder.
+func recorderOf(ctx context.Context, l *Logger) *recorder {
+	if l != nil && l.recorder != nil {
+		return l.recorder
+	}
+	if ctx != nil {
+		r, _ := ctx.Value(recorderKey{}).(*recorder)
+		return r
+	}
+	return nil
+}
+
+// record keeps a DEBUG or TRACE log message and returns true. If the recorder is full, the oldest log message
+// is discarded. Log messages accepted by a level override are not kept. An ERROR log message dumps the log
+// messages kept before it is sent by the caller.
+func (r *recorder) record(logMsg *logMessage) bool {
+	switch {
+	case logMsg.Level < INFO && !logMsg.override:
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		if r.count == len(r.messages) {
+			releaseLogMessage(r.messages[r.head])
+			r.head = (r.head + 1) % len(r.messages)
+			r.count--
+		}
+		r.messages[(r.head+r.count)%len(r.messages)] = logMsg
+		r.count++
+		return true
+	case logMsg.Level >= ERROR:
+		r.dump()
+	}
+	return false
+}
+
+// dump sends the log messages kept to the log service, the oldest one first. They are written regardless of
+// the levels of their log destinations. If the log service isn't running, e.g. if a deferred Dump is called
+// after Shutdown, the log messages are discarded.
+func (r *recorder) dump() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	active := s.isActive()
+	for ; r.count > 0; r.count-- {
+		logMsg := r.messages[r.head]
+		r.messages[r.head] = nil
+		r.head = (r.head + 1) % len(r.messages)
+		if !active {
+			releaseLogMessage(logMsg)
+			continue
+		}
+		logMsg.override = true
+		s.enqueue(logMsg)
+	}
+}
+
+// WithRecorder returns a child logger with a flight recorder: DEBUG and TRACE log messages written by the
+// child logger, or by loggers derived from it, are not written right away. Instead, the most recent of them
+// are kept in memory and written, regardless of the levels of their log destinations, as soon as an ERROR
+// log message is written by one of these loggers or Dump is called. Otherwise, they are discarded.
+// The values of the log messages kept are formatted when they are written, hence they must not be changed
+// after they were logged.
+// The size parameter specifies the maximum number of log messages kept.
+func (l *Logger) WithRecorder(size int) *Logger {
+	return &Logger{component: l.component, fields: l.fields, recorder: newRecorder(size)}
+}
+
+// Dump writes the log messages kept by the flight recorder of the logger (see WithRecorder) and clears it.
+// If the logger has no flight recorder, Dump does nothing.
+func (l *Logger) Dump() {
+	if l.recorder != nil {
+		l.recorder.dump()
+	}
+}
+
+// NewRecorderContext returns a copy of the parent context with a flight recorder, which works like the flight
+// recorder of a logger (see WithRecorder) for the log messages written with the context by WriteCtx or LogCtx,
+// e.g. for all log messages of a request. Loggers with a flight recorder of their own use theirs.
+// The ctx parameter specifies the parent context.
+// The size parameter specifies the maximum number of log messages kept.
+func NewRecorderContext(ctx context.Context, size int) context.Context {
+	return context.WithValue(ctx, recorderKey{}, newRecorder(size))
+}
+
+// DumpContext writes the log messages kept by the flight recorder of a context (see NewRecorderContext) and
+// clears it. If the context has no flight recorder, DumpContext does nothing.
+func DumpContext(ctx context.Context) {
+	if r, _ := ctx.Value(recorderKey{}).(*recorder); r != nil {
+		r.dump()
+	}
+}
@@ -0,0 +1,288 @@
+package simplelog
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ringPrefix is the prefix of the log records kept by a ring buffer, which are formatted by the TextFormatter.
+var ringPrefix = []string{"#2006-01-02T15:04:05.000000Z07:00#", "%LEVEL%"}
+
+// ringEntry represents a log record kept by a ring buffer. Ring entries are never changed once they are
+// created, hence they are shared by all readers of the ring buffer.
+type ringEntry struct {
+	rec  Record // the log record with its message, fields and stack trace; it has no values
+	line []byte // the log record formatted by the TextFormatter, including the trailing newline
+}
+
+// ringBuffer is a log destination which keeps the most recent log records in memory.
+type ringBuffer struct {
+	mutex       sync.Mutex                   // to serialize the access of the log service and the readers
+	entries     []*ringEntry                 // the log records, the oldest one at index head
+	head        int                          // the index of the oldest log record
+	count       int                          // the number of log records kept
+	size        int                          // the number of bytes of the formatted log records kept
+	maxSize     int                          // the maximum number of bytes of the formatted log records kept
+	level       Level                        // minimum level of log records kept
+	line        []byte                       // reusable buffer to format log records
+	subscribers map[chan *ringEntry]struct{} // the channels of the readers following the ring buffer
+}
+
+// newRingBuffer instantiates a new ring buffer, which keeps up to maxRecords log records and up to
+// maxSize bytes of formatted log records.
+func newRingBuffer(maxRecords, maxSize int) *ringBuffer {
+	return &ringBuffer{
+		entries:     make([]*ringEntry, maxRecords),
+		maxSize:     maxSize,
+		subscribers: make(map[chan *ringEntry]struct{}),
+	}
+}
+
+// write adds a log record to the ring buffer and passes it to all followers. The oldest log records are
+// dropped, as long as the ring buffer exceeds its bounds.
+func (r *ringBuffer) write(rec *Record) {
+	rec.Prefix, rec.Lines = ringPrefix, RawLines
+	r.line = TextFormatter{}.Format(r.line[:0], rec)
+	entry := &ringEntry{
+		rec: Record{
+			Time:      rec.Time,
+			Level:     rec.Level,
+			Component: rec.Component,
+			Msg:       rec.Message(),
+			Fields:    append([]Field(nil), rec.Fields...),
+			Stack:     rec.Stack,
+		},
+		line: append([]byte(nil), r.line...),
+	}
+	if cap(r.line) > maxRetainedBufSize {
+		r.line = nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.count == len(r.entries) {
+		r.drop()
+	}
+	r.entries[(r.head+r.count)%len(r.entries)] = entry
+	r.count++
+	r.size += len(entry.line)
+	for r.size > r.maxSize && r.count > 1 {
+		r.drop()
+	}
+	for ch := range r.subscribers {
+		select {
+		case ch <- entry:
+		default:
+			// the follower doesn't keep up; the log record is skipped for it
+		}
+	}
+}
+
+// drop drops the oldest log record.
+func (r *ringBuffer) drop() {
+	r.size -= len(r.entries[r.head].line)
+	r.entries[r.head] = nil
+	r.head = (r.head + 1) % len(r.entries)
+	r.count--
+}
+
+// snapshot returns the log records kept, the oldest one first.
+func (r *ringBuffer) snapshot() []*ringEntry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.snapshotLocked()
+}
+
+// snapshotLocked returns the log records kept, the oldest one first. The mutex has to be held.
+func (r *ringBuffer) snapshotLocked() []*ringEntry {
+	entries := make([]*ringEntry, r.count)
+	for i := range entries {
+		entries[i] = r.entries[(r.head+i)%len(r.entries)]
+	}
+	return entries
+}
+
+// subscribe returns the log records kept and a channel which receives all log records written afterwards,
+// until the ring buffer is closed or the channel is unsubscribed.
+func (r *ringBuffer) subscribe() ([]*ringEntry, chan *ringEntry) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ch := make(chan *ringEntry, 256)
+	if r.subscribers == nil {
+		// the ring buffer is closed already
+		close(ch)
+	} else {
+		r.subscribers[ch] = struct{}{}
+	}
+	return r.snapshotLocked(), ch
+}
+
+// unsubscribe stops passing log records to a channel returned by subscribe.
+func (r *ringBuffer) unsubscribe(ch chan *ringEntry) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.subscribers, ch)
+}
+
+// close closes the channels of all followers.
+func (r *ringBuffer) close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for ch := range r.subscribers {
+		close(ch)
+	}
+	r.subscribers = nil
+}
+
+// SetupRing sets up an additional log destination, which keeps the most recent log records in memory, e.g.
+// to look at them by the handler returned by RingHandler without access to the log files.
+// The ring buffer is identified by a name like a named log file and is closed by CloseLog. Its minimum level
+// can be set by SetLevel; the log records are formatted by the TextFormatter with the time and the level as prefix.
+// The name parameter specifies the name which identifies the ring buffer, e.g. "recent".
+// The maxRecords parameter specifies the maximum number of log records kept.
+// The maxSize parameter specifies the maximum number of bytes of the formatted log records kept; 0 means no limit.
+// The returned log destination can be combined with the other log destinations, e.g. FILE | ring.
+func SetupRing(name string, maxRecords, maxSize int) int {
+	if s.isActive() {
+		if maxRecords < 1 {
+			maxRecords = 1
+		}
+		if maxSize < 1 {
+			maxSize = math.MaxInt
+		}
+		destination := s.registerDestination(name)
+		s.configure(configMessage{initring, map[int]any{logdestination: destination, logring: newRingBuffer(maxRecords, maxSize)}})
+		return destination
+	} else {
+		panic(sg002)
+	}
+}
+
+// ringHandler implements the http.Handler returned by RingHandler.
+type ringHandler struct {
+	destination int // the log destination of the ring buffer
+}
+
+// RingHandler returns an http.Handler which serves the log records kept by a ring buffer set up by SetupRing.
+// The log records are written as text, or, with the query parameter format=json, as JSON lines. With the query
+// parameter follow=1 or if the request accepts text/event-stream, the log records are streamed as Server-Sent
+// Events: first the log records kept, then all log records written afterwards, until the client disconnects.
+// Followers which don't keep up skip log records. The query parameters level (e.g. level=warn) and q (a substring
+// of the text line of a log record) filter the log records.
+// The destination parameter specifies the log destination returned by SetupRing.
+// The handler doesn't authenticate requests; it must only be reachable by operators.
+func RingHandler(destination int) http.Handler {
+	return ringHandler{destination}
+}
+
+// ServeHTTP denotes the http.Handler interface implementation by the ringHandler type.
+func (h ringHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.isActive() {
+		http.Error(w, sg002, http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var ring *ringBuffer
+	if s.isRingDestination(h.destination) {
+		s.configure(configMessage{getring, map[int]any{logdestination: h.destination, logring: &ring}})
+	}
+	if ring == nil {
+		http.Error(w, sg003, http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	level := Level(math.MinInt)
+	if name := query.Get("level"); name != "" {
+		var ok bool
+		if level, ok = ParseLevel(name); !ok {
+			http.Error(w, "unknown log level specified", http.StatusBadRequest)
+			return
+		}
+	}
+	filter := ringFilter{level: level, substring: query.Get("q"), json: query.Get("format") == "json"}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok || query.Get("follow") == "" && !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		if filter.json {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		} else {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		var buf []byte
+		for _, entry := range ring.snapshot() {
+			buf = filter.append(buf, entry)
+		}
+		w.Write(buf)
+		return
+	}
+
+	entries, ch := ring.subscribe()
+	defer ring.unsubscribe(ch)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	var buf []byte
+	for _, entry := range entries {
+		buf = filter.appendEvent(buf, entry)
+	}
+	for {
+		if len(buf) > 0 {
+			if _, err := w.Write(buf); err != nil {
+				return
+			}
+			buf = buf[:0]
+		}
+		flusher.Flush()
+		select {
+		case entry, ok := <-ch:
+			if !ok {
+				return
+			}
+			buf = filter.appendEvent(buf, entry)
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// ringFilter represents the filter and the format of the log records served by the ring handler.
+type ringFilter struct {
+	level     Level  // the minimum level of log records
+	substring string // the substring the text line of a log record has to contain
+	json      bool   // true, if log records are formatted as JSON lines
+}
+
+// append appends a log record to buf, if it matches the filter.
+func (f *ringFilter) append(buf []byte, entry *ringEntry) []byte {
+	if entry.rec.Level < f.level || !bytes.Contains(entry.line, []byte(f.substring)) {
+		return buf
+	}
+	if f.json {
+		return JSONFormatter{}.Format(buf, &entry.rec)
+	}
+	return append(buf, entry.line...)
+}
+
+// appendEvent appends a log record as Server-Sent Event to buf, if it matches the filter.
+// Each line of the formatted log record is sent as data line of the event.
+func (f *ringFilter) appendEvent(buf []byte, entry *ringEntry) []byte {
+	start := len(buf)
+	buf = f.append(buf, entry)
+	if len(buf) == start {
+		return buf
+	}
+	lines := string(buf[start : len(buf)-1])
+	buf = buf[:start]
+	for _, line := range strings.Split(lines, "\n") {
+		buf = append(buf, "data: "...)
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+	return append(buf, '\n')
+}
@@ -2,35 +2,158 @@ package simplelog
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
-	s = new(simpleLogService) // create instance of a simplelog service
+	s              = new(simpleLogService)                                 // create instance of a simplelog service
+	logMessagePool = sync.Pool{New: func() any { return new(logMessage) }} // pool of reusable log messages
 )
 
 // simpleLogService represents an object used to handle workflows triggered by the simplelog exported functions.
 type simpleLogService struct {
-	active                bool               // flag to indicate whether the log service is up and running
-	stdoutLogger                             // the stdout logger instance
-	fileLogger                               // the file logger instance
-	dataQueue             chan logMessage    // to receive log data from the caller; this channel is buffered
-	configService         chan configMessage // to receive config service requests from the caller
-	configServiceResponse chan error         // to send an error response to the caller to continue the workflow
-	stopService           chan bool          // to receive a stop service request from the caller
-	stopServiceResponse   chan struct{}      // to send a signal to the caller to continue the workflow
+	namedDestinations     int64               // bit mask of the log destinations of named log files; accessed atomically
+	ringDestinations      int64               // bit mask of the log destinations of ring buffers; accessed atomically
+	stackTrace            int32               // 1, if stack traces are attached to ERROR log records; accessed atomically
+	levelOverrides        atomic.Value        // the level overrides (*levelOverrides), see SetLevelOverrides
+	active                int32               // 1, if the log service is up and running; accessed atomically
+	synchronous           bool                // flag to indicate whether log messages are written by the caller (see StartupSync)
+	syncMutex             sync.Mutex          // to serialize the writes and config requests in synchronous mode
+	lastCheck             time.Time           // the time the log files were last checked in synchronous mode
+	stdoutLogger                              // the stdout logger instance
+	fileLogger                                // the file logger instance
+	namedFileLoggers      map[int]*fileLogger // the named file logger instances, keyed by their log destination
+	rings                 map[int]*ringBuffer // the ring buffers, keyed by their log destination
+	batchSize             int                 // maximum number of log messages processed and written at once
+	destinationNames      map[string]int      // the log destinations of named log files, keyed by their name
+	destinationMutex      sync.Mutex          // to serialize the registration of named log files
+	dataQueue             chan *logMessage    // to receive log data from the caller; this channel is buffered
+	shardedQueue          *shardedQueue       // to receive log data from the caller instead of dataQueue; nil if not used
+	shardedBatch          []*logMessage       // the batch of log messages taken from the sharded queue
+	configService         chan configMessage  // to receive config service requests from the caller
+	configServiceResponse chan error          // to send an error response to the caller to continue the workflow
+	stopService           chan bool           // to receive a stop service request from the caller
+	stopServiceResponse   chan struct{}       // to send a signal to the caller to continue the workflow
 }
 
 // isActive returns true, if the log service is up and running, false otherwise.
+// It is false as soon as the log service is being stopped.
 func (s *simpleLogService) isActive() bool {
-	return s.active
+	return atomic.LoadInt32(&s.active) == 1
 }
 
 // setActive sets the active flag of the log service.
 func (s *simpleLogService) setActive(state bool) {
-	s.active = state
+	var active int32
+	if state {
+		active = 1
+	}
+	atomic.StoreInt32(&s.active, active)
+}
+
+// deactivate clears the active flag of the log service and returns true, if it was set.
+func (s *simpleLogService) deactivate() bool {
+	return atomic.CompareAndSwapInt32(&s.active, 1, 0)
+}
+
+// isStackTrace returns true, if stack traces are attached to ERROR log records, false otherwise.
+func (s *simpleLogService) isStackTrace() bool {
+	return atomic.LoadInt32(&s.stackTrace) == 1
+}
+
+// setStackTrace sets whether stack traces are attached to ERROR log records.
+func (s *simpleLogService) setStackTrace(enabled bool) {
+	var state int32
+	if enabled {
+		state = 1
+	}
+	atomic.StoreInt32(&s.stackTrace, state)
+}
+
+// registerDestination reserves a log destination for a named log file and returns it.
+// The lowest bit which is neither used by STDOUT, FILE nor by another named log file is reserved.
+func (s *simpleLogService) registerDestination(name string) int {
+	s.destinationMutex.Lock()
+	defer s.destinationMutex.Unlock()
+	if _, ok := s.destinationNames[name]; ok || name == "" {
+		panic(sg005)
+	}
+	if s.destinationNames == nil {
+		s.destinationNames = make(map[string]int)
+	}
+	used := int(atomic.LoadInt64(&s.namedDestinations)) | MULTI
+	for destination := FILE << 1; destination > 0; destination <<= 1 {
+		if used&destination == 0 {
+			s.destinationNames[name] = destination
+			atomic.StoreInt64(&s.namedDestinations, int64(used&^MULTI|destination))
+			return destination
+		}
+	}
+	panic(sg007)
+}
+
+// unregisterDestination releases the log destination of a named log file.
+func (s *simpleLogService) unregisterDestination(name string) {
+	s.destinationMutex.Lock()
+	defer s.destinationMutex.Unlock()
+	if destination, ok := s.destinationNames[name]; ok {
+		delete(s.destinationNames, name)
+		atomic.StoreInt64(&s.namedDestinations, atomic.LoadInt64(&s.namedDestinations)&^int64(destination))
+		atomic.StoreInt64(&s.ringDestinations, atomic.LoadInt64(&s.ringDestinations)&^int64(destination))
+	}
+}
+
+// resetDestinations releases the log destinations of all named log files.
+func (s *simpleLogService) resetDestinations() {
+	s.destinationMutex.Lock()
+	defer s.destinationMutex.Unlock()
+	s.destinationNames = nil
+	atomic.StoreInt64(&s.namedDestinations, 0)
+	atomic.StoreInt64(&s.ringDestinations, 0)
+}
+
+// destination returns the log destination of a named log file.
+// The ok result is false, if no log file with the given name is set up.
+func (s *simpleLogService) destination(name string) (destination int, ok bool) {
+	s.destinationMutex.Lock()
+	defer s.destinationMutex.Unlock()
+	destination, ok = s.destinationNames[name]
+	return destination, ok
+}
+
+// names returns the names of all named log files.
+func (s *simpleLogService) names() []string {
+	s.destinationMutex.Lock()
+	defer s.destinationMutex.Unlock()
+	names := make([]string, 0, len(s.destinationNames))
+	for name := range s.destinationNames {
+		names = append(names, name)
+	}
+	return names
+}
+
+// isValidDestination returns true, if the destination consists of known log destinations only.
+func (s *simpleLogService) isValidDestination(destination int) bool {
+	known := int(atomic.LoadInt64(&s.namedDestinations)) | MULTI
+	return destination != 0 && destination&^known == 0
+}
+
+// isFileDestination returns true, if the destination denotes exactly one file log destination,
+// i.e. either FILE or the log destination of a named log file.
+func (s *simpleLogService) isFileDestination(destination int) bool {
+	return destination != STDOUT && destination&(destination-1) == 0 && s.isValidDestination(destination) &&
+		!s.isRingDestination(destination)
+}
+
+// isRingDestination returns true, if the destination denotes exactly one ring buffer.
+func (s *simpleLogService) isRingDestination(destination int) bool {
+	return destination != 0 && destination&(destination-1) == 0 && destination&int(atomic.LoadInt64(&s.ringDestinations)) != 0
 }
 
 // instance denotes the logWriter interface implementation by the stdoutLogger type.
@@ -60,42 +183,152 @@ func simpleLogger(lw logWriter) *logger {
 	return lw.instance()
 }
 
+// fileLoggerOf returns the file logger instance of a file log destination or nil, if there is none.
+func (s *simpleLogService) fileLoggerOf(destination int) *fileLogger {
+	if destination == FILE {
+		return &s.fileLogger
+	}
+	return s.namedFileLoggers[destination]
+}
+
 // setupLogFile creates and opens the log file.
 func (f *fileLogger) setupLogFile(flag int, logName string) error {
 	var err error
-	f.desc, err = os.OpenFile(logName, flag, 0644)
+	if f.desc, err = os.OpenFile(logName, flag, 0644); err != nil {
+		return err
+	}
+	info, err := f.desc.Stat()
+	if err != nil {
+		return err
+	}
+	f.size = info.Size()
+	f.flag = flag
 	return err
 }
 
+// write writes a log record to the log file and rotates the log file once it reached its maximum size.
+func (f *fileLogger) write(rec *Record) {
+	rec.Prefix, rec.Lines = f.prefix, f.lines
+	f.size += int64(simpleLogger(f).write(f.formatter, rec, f.stripANSI, f.maxRecordSize))
+	if f.maxSize > 0 && f.size >= f.maxSize {
+		if err := f.rotateLogFile(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// rotateLogFile archives the log file and continues with a new, empty log file of the same name.
+func (f *fileLogger) rotateLogFile() error {
+	logName := f.desc.Name()
+	if err := f.releaseFileLogger(true); err != nil {
+		return err
+	}
+	return f.setupLogFile(os.O_TRUNC|os.O_CREATE|os.O_WRONLY, logName)
+}
+
+// reopenLogFile closes the log file and opens it again by its name, e.g. after it was renamed by an external
+// log rotation tool. The log file is opened with its original flags, except that it is never truncated,
+// that it is created if it doesn't exist and that it is always opened for writing, hence no log data is lost.
+// The log file is opened again before the current one is closed; if this fails, the current log file is kept.
+func (f *fileLogger) reopenLogFile() error {
+	if f.desc == nil {
+		return nil
+	}
+	reopened := new(fileLogger)
+	if err := reopened.setupLogFile(f.flag&^(os.O_TRUNC|os.O_EXCL|os.O_RDWR)|os.O_APPEND|os.O_CREATE|os.O_WRONLY, f.desc.Name()); err != nil {
+		return err
+	}
+	if err := f.releaseFileLogger(false); err != nil {
+		reopened.releaseFileLogger(false)
+		return err
+	}
+	f.desc, f.flag, f.size = reopened.desc, reopened.flag, reopened.size
+	return nil
+}
+
+// checkLogFile checks whether the log file was moved or removed, e.g. by an operator, in which case the
+// logs would silently vanish into an unlinked file. If so, the log file is transparently reopened and a
+// notice log record is written to it. If the log file can't be reopened, logging continues into the current
+// log file and the check is repeated next time.
+func (f *fileLogger) checkLogFile() {
+	if f.desc == nil {
+		return
+	}
+	current, err := f.desc.Stat()
+	if err != nil {
+		return
+	}
+	logName := f.desc.Name()
+	if info, err := os.Stat(logName); err == nil && os.SameFile(current, info) || err != nil && !os.IsNotExist(err) {
+		return
+	}
+	if err = f.reopenLogFile(); err == nil {
+		f.write(&Record{Time: time.Now(), Level: WARN, Values: []any{"log file", logName, "was moved or removed and has been recreated"}})
+		f.self.writeBatch()
+	}
+}
+
+// syncLogFile flushes the log file buffer and commits the log file to stable storage.
+func (f *fileLogger) syncLogFile() error {
+	if f.desc == nil {
+		return nil
+	}
+	f.flushBuffer()
+	return f.desc.Sync()
+}
+
+// flushBuffer flushes the log file buffer, if it has data to be written.
+func (f *fileLogger) flushBuffer() {
+	if f.writer != nil {
+		// only do the flush when the buffer has data to be written
+		if f.writer.Buffered() > 0 {
+			f.writer.Flush()
+		}
+	}
+}
+
 // releaseFileLogger releases all fileLogger resources.
 func (f *fileLogger) releaseFileLogger(archive bool) error {
 	var err error
 	if f.self != nil {
+		f.self.writeBatch()
 		if f.writer.Buffered() >= 0 {
 			// only do the flush when the buffer has data to be written
 			f.writer.Flush()
 		}
 	}
+	if f.desc == nil {
+		return err
+	}
+	logName := f.desc.Name()
 	if err = f.desc.Close(); err != nil {
 		return err
 	}
 	if archive {
-		if err = s.archiveLogFile(s.desc.Name()); err != nil {
+		if err = f.archiveLogFile(logName); err != nil {
 			return err
 		}
 	}
 	f.writer = nil
 	f.desc = nil
 	f.self = nil
+	f.size = 0
 	return err
 }
 
 // archiveLogFile archives the log file.
+// If an archive with the same timestamp already exists, a sequence number is appended to the archive name.
 func (f *fileLogger) archiveLogFile(logFileName string) error {
 	var err error
 	t := time.Now()
 	formatted := fmt.Sprintf("%d%02d%02d%02d%02d%02d", t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second())
 	logArchiveName := logFileName + "_" + formatted
+	for i := 1; ; i++ {
+		if _, err = os.Stat(logArchiveName); os.IsNotExist(err) {
+			break
+		}
+		logArchiveName = fmt.Sprintf("%s_%s.%d", logFileName, formatted, i)
+	}
 	err = os.Rename(logFileName, logArchiveName)
 	return err
 }
@@ -111,9 +344,34 @@ func (f *fileLogger) changeLogFile(flag int, newLogName string) error {
 	return err
 }
 
+// releaseFileLoggers releases the resources of all file loggers.
+func (s *simpleLogService) releaseFileLoggers(archive bool) {
+	s.releaseFileLogger(archive)
+	for destination, f := range s.namedFileLoggers {
+		f.releaseFileLogger(archive)
+		delete(s.namedFileLoggers, destination)
+	}
+}
+
+// releaseRings closes all ring buffers.
+func (s *simpleLogService) releaseRings() {
+	for destination, r := range s.rings {
+		r.close()
+		delete(s.rings, destination)
+	}
+}
+
 // stop stops the log service.
 // A part of this step the underlying goroutine is also stopped.
 func (s *simpleLogService) stop(archivelog bool) {
+	if s.synchronous {
+		s.syncMutex.Lock()
+		defer s.syncMutex.Unlock()
+		s.writeBatches()
+		s.releaseFileLoggers(archivelog)
+		s.releaseRings()
+		return
+	}
 	s.stopService <- archivelog
 	<-s.stopServiceResponse
 }
@@ -122,16 +380,20 @@ func (s *simpleLogService) stop(archivelog bool) {
 // This function is kicked off in a dedicated goroutine.
 // It handles client requests by listening on the following channels:
 //   - stopService
-//   - dataQueue
+//   - dataQueue or the ready channel of the sharded queue
 //   - configService
 func (s *simpleLogService) run(serviceRunning chan<- bool) {
-	var logData logMessage
+	var logData *logMessage
+	var queueReady chan struct{}
+	if s.shardedQueue != nil {
+		queueReady = s.shardedQueue.ready
+	}
 	var cfgData configMessage
 
 	defer close(s.stopServiceResponse)
 
 	// ticker to periodically trigger a flush of the log file buffer
-	flushBufferInterval := time.NewTicker(1000 * time.Millisecond)
+	flushBufferInterval := time.NewTicker(flushInterval)
 
 	// service loop
 	for {
@@ -139,66 +401,387 @@ func (s *simpleLogService) run(serviceRunning chan<- bool) {
 		case serviceRunning <- true:
 		case archivelog := <-s.stopService:
 			flush()
-			s.releaseFileLogger(archivelog)
+			s.releaseFileLoggers(archivelog)
+			s.releaseRings()
 			return
 		case logData = <-s.dataQueue:
-			writeMessage(&logData)
+			// process a batch of pending log messages, writing it with a single write per log destination
+			writeMessage(logData)
+			for n := 1; n < s.batchSize && len(s.dataQueue) > 0; n++ {
+				writeMessage(<-s.dataQueue)
+			}
+			s.writeBatches()
+		case <-queueReady:
+			// process the pending log messages; log messages pushed afterwards are signaled again
+			s.writeShardedBatch()
 		case <-flushBufferInterval.C:
-			if s.writer != nil {
-				// only do the flush when the buffer has data to be written
-				if s.writer.Buffered() > 0 {
-					s.writer.Flush()
-				}
+			s.fileLogger.flushBuffer()
+			s.fileLogger.checkLogFile()
+			for _, f := range s.namedFileLoggers {
+				f.flushBuffer()
+				f.checkLogFile()
 			}
 		case cfgData = <-s.configService:
-			switch cfgData.task {
-			case initlog:
-				flag := cfgData.data[logflag].(int)
-				logName := cfgData.data[logfilename].(string)
-				err := s.setupLogFile(flag, logName)
-				s.configServiceResponse <- err
-			case switchlog:
-				flush()
-				flag := cfgData.data[logflag].(int)
-				newLogName := cfgData.data[logfilename].(string)
-				err := s.changeLogFile(flag, newLogName)
-				s.configServiceResponse <- err
-			case setprefix:
-				if logPrefix, ok := cfgData.data[stdoutlogprefix]; ok {
-					s.stdoutLogger.prefix = logPrefix.([]string)
-				} else if logPrefix, ok = cfgData.data[filelogprefix]; ok {
-					s.fileLogger.prefix = logPrefix.([]string)
-				} else {
-					panic(sg003)
+			s.configServiceResponse <- s.handleConfig(cfgData)
+		}
+	}
+}
+
+// configure passes a config service request to the log service and returns its result.
+// In synchronous mode, the request is handled in the caller's goroutine.
+// If the log service is stopped before it took the request, e.g. by a concurrent Shutdown, the request
+// isn't handled and an error is returned.
+func (s *simpleLogService) configure(cfgData configMessage) error {
+	if s.synchronous {
+		s.syncMutex.Lock()
+		defer s.syncMutex.Unlock()
+		if !s.isActive() {
+			return errors.New(sg000)
+		}
+		return s.handleConfig(cfgData)
+	}
+	select {
+	case s.configService <- cfgData:
+		return <-s.configServiceResponse
+	case <-s.stopServiceResponse:
+		return errors.New(sg000)
+	}
+}
+
+// handleConfig handles a config service request and returns its result.
+// In service mode, it is called by the log service goroutine, in synchronous mode by the caller while the
+// mutex is held.
+func (s *simpleLogService) handleConfig(cfgData configMessage) error {
+	switch cfgData.task {
+	case initlog:
+		flag := cfgData.data[logflag].(int)
+		logName := cfgData.data[logfilename].(string)
+		if destination, ok := cfgData.data[logdestination]; ok {
+			f := new(fileLogger)
+			err := f.setupLogFile(flag, logName)
+			if err == nil {
+				if s.namedFileLoggers == nil {
+					s.namedFileLoggers = make(map[int]*fileLogger)
 				}
-				s.configServiceResponse <- nil
+				s.namedFileLoggers[destination.(int)] = f
+			}
+			return err
+		}
+		return s.setupLogFile(flag, logName)
+	case switchlog:
+		flush()
+		flag := cfgData.data[logflag].(int)
+		newLogName := cfgData.data[logfilename].(string)
+		return s.fileLoggerOf(cfgData.data[logdestination].(int)).changeLogFile(flag, newLogName)
+	case setprefix:
+		if logPrefix, ok := cfgData.data[stdoutlogprefix]; ok {
+			s.stdoutLogger.prefix = logPrefix.([]string)
+		} else if logPrefix, ok = cfgData.data[filelogprefix]; ok {
+			s.fileLoggerOf(cfgData.data[logdestination].(int)).prefix = logPrefix.([]string)
+		} else {
+			panic(sg003)
+		}
+		return nil
+	case closelog:
+		flush()
+		destination := cfgData.data[logdestination].(int)
+		if r, ok := s.rings[destination]; ok {
+			r.close()
+			delete(s.rings, destination)
+			return nil
+		}
+		err := s.fileLoggerOf(destination).releaseFileLogger(cfgData.data[logarchive].(bool))
+		delete(s.namedFileLoggers, destination)
+		return err
+	case setrotation:
+		s.fileLoggerOf(cfgData.data[logdestination].(int)).maxSize = cfgData.data[logmaxsize].(int64)
+		return nil
+	case setformatter:
+		formatter, _ := cfgData.data[logformatter].(Formatter)
+		if destination := cfgData.data[logdestination].(int); destination == STDOUT {
+			s.stdoutLogger.formatter = formatter
+		} else {
+			s.fileLoggerOf(destination).formatter = formatter
+		}
+		return nil
+	case setlinepolicy:
+		policy := cfgData.data[loglinepolicy].(LinePolicy)
+		if destination := cfgData.data[logdestination].(int); destination == STDOUT {
+			s.stdoutLogger.lines = policy
+		} else {
+			s.fileLoggerOf(destination).lines = policy
+		}
+		return nil
+	case setmaxrecordsize:
+		maxSize := cfgData.data[logmaxrecordsize].(int)
+		if destination := cfgData.data[logdestination].(int); destination == STDOUT {
+			s.stdoutLogger.maxRecordSize = maxSize
+		} else {
+			s.fileLoggerOf(destination).maxRecordSize = maxSize
+		}
+		return nil
+	case setstripansi:
+		s.fileLoggerOf(cfgData.data[logdestination].(int)).stripANSI = cfgData.data[logstripansi].(bool)
+		return nil
+	case setlevel:
+		level := cfgData.data[loglevel].(Level)
+		if destination := cfgData.data[logdestination].(int); destination == STDOUT {
+			s.stdoutLogger.level = level
+		} else if r, ok := s.rings[destination]; ok {
+			r.level = level
+		} else {
+			s.fileLoggerOf(destination).level = level
+		}
+		return nil
+	case applyconfig:
+		flush()
+		return s.applyConfig(cfgData.data[logconfig].(*Config), cfgData.data[logdestinations].(map[string]int))
+	case reopenlog:
+		flush()
+		err := s.fileLogger.reopenLogFile()
+		for _, f := range s.namedFileLoggers {
+			if e := f.reopenLogFile(); err == nil {
+				err = e
 			}
 		}
+		return err
+	case synclog:
+		flush()
+		err := s.fileLogger.syncLogFile()
+		for _, f := range s.namedFileLoggers {
+			if e := f.syncLogFile(); err == nil {
+				err = e
+			}
+		}
+		return err
+	case setbatchsize:
+		s.batchSize = cfgData.data[logbatchsize].(int)
+		return nil
+	case setoverrides:
+		s.levelOverrides.Store(cfgData.data[logoverrides].(*levelOverrides))
+		return nil
+	case initring:
+		if s.rings == nil {
+			s.rings = make(map[int]*ringBuffer)
+		}
+		destination := cfgData.data[logdestination].(int)
+		s.rings[destination] = cfgData.data[logring].(*ringBuffer)
+		atomic.StoreInt64(&s.ringDestinations, atomic.LoadInt64(&s.ringDestinations)|int64(destination))
+		return nil
+	case getring:
+		*cfgData.data[logring].(**ringBuffer) = s.rings[cfgData.data[logdestination].(int)]
+		return nil
+	case getstate:
+		s.state(cfgData.data[logstate].(*adminState))
+		return nil
+	case rotatelog:
+		flush()
+		f := s.fileLoggerOf(cfgData.data[logdestination].(int))
+		if f == nil || f.desc == nil {
+			return errors.New(sg004)
+		}
+		return f.rotateLogFile()
 	}
+	return nil
 }
 
 // writeMessage writes data of log messages to a dedicated destination.
+// The log message is put back into the pool afterwards.
 func writeMessage(logMsg *logMessage) {
-	switch logMsg.destination {
-	case STDOUT:
-		simpleLogger(&s.stdoutLogger).write(logMsg)
-	case FILE:
-		simpleLogger(&s.fileLogger).write(logMsg)
-	case MULTI:
-		logMsg.destination = MULTI & STDOUT
-		simpleLogger(&s.stdoutLogger).write(logMsg)
-		logMsg.destination = MULTI & FILE
-		simpleLogger(&s.fileLogger).write(logMsg)
+	defer releaseLogMessage(logMsg)
+	accepting := s.acceptingDestinations(logMsg)
+	if accepting == 0 {
+		// no log destination accepts the level of the log message; lazy values are never evaluated
+		return
+	}
+	rec := &logMsg.Record
+	resolveValues(rec.Values)
+	if accepting&STDOUT != 0 {
+		rec.Prefix, rec.Lines = s.stdoutLogger.prefix, s.stdoutLogger.lines
+		simpleLogger(&s.stdoutLogger).write(s.stdoutLogger.formatter, rec, false, s.stdoutLogger.maxRecordSize)
+	}
+	if accepting&FILE != 0 {
+		s.fileLogger.write(rec)
+	}
+	if accepting&^MULTI != 0 {
+		// named log files
+		for destination, f := range s.namedFileLoggers {
+			if accepting&destination != 0 {
+				f.write(rec)
+			}
+		}
+		for destination, r := range s.rings {
+			if accepting&destination != 0 {
+				r.write(rec)
+			}
+		}
+	}
+}
+
+// newLogMessage returns a log message from the pool, which is initialized with the current time,
+// the given level and the given log destination.
+func newLogMessage(level Level, destination int) *logMessage {
+	if s.isActive() {
+		if !s.isValidDestination(destination) {
+			panic(sg003)
+		}
+		logMsg := logMessagePool.Get().(*logMessage)
+		logMsg.destination = destination
+		logMsg.Time = time.Now()
+		logMsg.Level = level
+		return logMsg
+	} else {
+		panic(sg002)
+	}
+}
+
+// releaseLogMessage resets a log message and puts it back into the pool.
+// The values and fields are cleared, so that the pool doesn't keep them alive.
+func releaseLogMessage(logMsg *logMessage) {
+	for i := range logMsg.Values {
+		logMsg.Values[i] = nil
+	}
+	for i := range logMsg.Fields {
+		logMsg.Fields[i] = Field{}
+	}
+	logMsg.Record = Record{Values: logMsg.Values[:0], Fields: logMsg.Fields[:0]}
+	logMsg.override = false
+	logMessagePool.Put(logMsg)
+}
+
+// acceptingDestinations returns the log destinations of a log message whose minimum level is lower than
+// or equal to the level of the log message. If the level was accepted by a level override, all log
+// destinations of the log message are returned.
+func (s *simpleLogService) acceptingDestinations(logMsg *logMessage) int {
+	if logMsg.override {
+		// the level was accepted by a level override already
+		return logMsg.destination
+	}
+	var accepting int
+	if logMsg.destination&STDOUT != 0 && logMsg.Level >= s.stdoutLogger.level {
+		accepting |= STDOUT
+	}
+	if logMsg.destination&FILE != 0 && logMsg.Level >= s.fileLogger.level {
+		accepting |= FILE
+	}
+	if logMsg.destination&^MULTI != 0 {
+		for destination, f := range s.namedFileLoggers {
+			if logMsg.destination&destination != 0 && logMsg.Level >= f.level {
+				accepting |= destination
+			}
+		}
+		for destination, r := range s.rings {
+			if logMsg.destination&destination != 0 && logMsg.Level >= r.level {
+				accepting |= destination
+			}
+		}
+	}
+	return accepting
+}
+
+// enqueue sends a log message to the log service, either by the data channel or by the sharded queue.
+// If the buffer is full, enqueue blocks until the log service has taken a log message.
+func (s *simpleLogService) enqueue(logMsg *logMessage) {
+	if s.synchronous {
+		s.writeSync(logMsg)
+	} else if s.shardedQueue != nil {
+		s.shardedQueue.push(logMsg)
+	} else {
+		s.dataQueue <- logMsg
 	}
+}
 
+// writeSync writes a log message in the caller's goroutine and flushes the log file buffers, hence the log
+// message has reached its log destinations when writeSync returns. It is used in synchronous mode instead
+// of the log service goroutine, which also checks the log files once per interval otherwise.
+// If the log service was stopped by a concurrent Shutdown, the log message is discarded.
+func (s *simpleLogService) writeSync(logMsg *logMessage) {
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
+	if !s.isActive() {
+		releaseLogMessage(logMsg)
+		return
+	}
+	writeMessage(logMsg)
+	s.writeBatches()
+	s.fileLogger.flushBuffer()
+	for _, f := range s.namedFileLoggers {
+		f.flushBuffer()
+	}
+	if time.Since(s.lastCheck) >= flushInterval {
+		s.lastCheck = time.Now()
+		s.fileLogger.checkLogFile()
+		for _, f := range s.namedFileLoggers {
+			f.checkLogFile()
+		}
+	}
 }
 
-// flush flushes(writes) messages, which are still buffered in the data channel
+// enqueueCtx sends a log message to the log service like enqueue. If the buffer is full, enqueueCtx blocks until
+// either the log service has taken a log message or the context is done, in which case the log message is dropped.
+func (s *simpleLogService) enqueueCtx(ctx context.Context, logMsg *logMessage) {
+	switch {
+	case s.synchronous:
+		s.writeSync(logMsg)
+	case s.shardedQueue != nil:
+		if !s.shardedQueue.pushCtx(ctx, logMsg) {
+			releaseLogMessage(logMsg)
+		}
+	default:
+		select {
+		case s.dataQueue <- logMsg:
+		default:
+			// the buffer is full
+			select {
+			case s.dataQueue <- logMsg:
+			case <-ctx.Done():
+				releaseLogMessage(logMsg)
+			}
+		}
+	}
+}
+
+// flush flushes(writes) messages, which are still buffered in the data channel or the sharded queue
 // and not yet wrtitten do disc.
 func flush() {
-	var m logMessage
+	var m *logMessage
 	for len(s.dataQueue) > 0 {
 		m = <-s.dataQueue
-		writeMessage(&m)
+		writeMessage(m)
+	}
+	if s.shardedQueue != nil {
+		for s.shardedQueue.len() > 0 {
+			s.writeShardedBatch()
+		}
+	}
+	s.writeBatches()
+}
+
+// writeShardedBatch takes a batch of pending log messages from the sharded queue and writes it, with a
+// single write per log destination for up to batchSize log messages.
+func (s *simpleLogService) writeShardedBatch() {
+	s.shardedBatch = s.shardedQueue.popBatch(s.shardedBatch[:0])
+	for i, logMsg := range s.shardedBatch {
+		writeMessage(logMsg)
+		s.shardedBatch[i] = nil
+		if (i+1)%s.batchSize == 0 {
+			s.writeBatches()
+		}
+	}
+	s.writeBatches()
+}
+
+// writeBatches writes the current batches of log records of all log destinations.
+func (s *simpleLogService) writeBatches() {
+	if s.stdoutLogger.self != nil {
+		s.stdoutLogger.self.writeBatch()
+	}
+	if s.fileLogger.self != nil {
+		s.fileLogger.self.writeBatch()
+	}
+	for _, f := range s.namedFileLoggers {
+		if f.self != nil {
+			f.self.writeBatch()
+		}
 	}
 }
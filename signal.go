@@ -0,0 +1,84 @@
+package simplelog
+
+import (
+	"os"
+	"os/signal"
+)
+
+// ReloadOnSignal installs a signal handler which reloads the configuration file by calling Reload whenever
+// one of the given signals is received. If no signal is given, SIGHUP is used; on platforms other than Unix and
+// Windows, which don't know SIGHUP, the signal handler is only installed if at least one signal is given.
+// Failed reloads keep the current configuration and are reported by calling report; if report is nil,
+// the error is written as ERROR log record to STDOUT. Signals received while the log service is stopped
+// are ignored.
+// The path parameter specifies the configuration file, see LoadConfig.
+// The returned function uninstalls the signal handler again.
+func ReloadOnSignal(path string, report func(error), sig ...os.Signal) (stop func()) {
+	if len(sig) == 0 {
+		sig = reloadSignals
+	}
+	return handleSignal(func() {
+		if err := reload(path); err != nil {
+			reportSignalError(report, "reload of log configuration failed:", err)
+		}
+	}, sig)
+}
+
+// ReopenOnSignal installs a signal handler which reopens all log files by calling Reopen whenever one of
+// the given signals is received. If no signal is given, SIGUSR1 is used; on Windows and other platforms which
+// don't know SIGUSR1, the signal handler is only installed if at least one signal is given.
+// Failed reopens are reported by calling report; if report is nil, the error is written as ERROR log record
+// to STDOUT.
+// The returned function uninstalls the signal handler again.
+func ReopenOnSignal(report func(error), sig ...os.Signal) (stop func()) {
+	if len(sig) == 0 {
+		sig = reopenSignals
+	}
+	return handleSignal(func() {
+		if err := reopen(); err != nil {
+			reportSignalError(report, "reopen of log files failed:", err)
+		}
+	}, sig)
+}
+
+// reportSignalError reports the error of a signal handler by calling report or, if report is nil, by writing
+// it as ERROR log record with the given message to STDOUT. Errors of a log service which was stopped while
+// the signal was handled are ignored.
+func reportSignalError(report func(error), msg string, err error) {
+	if !s.isActive() {
+		return
+	}
+	if report != nil {
+		report(err)
+	} else {
+		WriteLevel(ERROR, STDOUT, msg, err)
+	}
+}
+
+// handleSignal calls handler in a dedicated goroutine whenever one of the given signals is received,
+// as long as the log service is running. If no signal is given, nothing is installed.
+// The returned function stops the goroutine.
+func handleSignal(handler func(), sig []os.Signal) (stop func()) {
+	if len(sig) == 0 {
+		return func() {}
+	}
+	received := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(received, sig...)
+	go func() {
+		for {
+			select {
+			case <-received:
+				if s.isActive() {
+					handler()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(received)
+		close(done)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package simplelog
+
+import (
+	"os"
+)
+
+// reloadSignals are the signals handled by ReloadOnSignal by default.
+// This platform doesn't know SIGHUP, hence the signals have to be given explicitly.
+var reloadSignals []os.Signal
+
+// reopenSignals are the signals handled by ReopenOnSignal by default.
+// This platform doesn't know SIGUSR1, hence the signals have to be given explicitly.
+var reopenSignals []os.Signal
@@ -0,0 +1,14 @@
+//go:build unix
+
+package simplelog
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals are the signals handled by ReloadOnSignal by default.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
+
+// reopenSignals are the signals handled by ReopenOnSignal by default.
+var reopenSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package simplelog
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals are the signals handled by ReloadOnSignal by default.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
+
+// reopenSignals are the signals handled by ReopenOnSignal by default.
+// Windows doesn't know SIGUSR1, hence the signals have to be given explicitly.
+var reopenSignals []os.Signal
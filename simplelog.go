@@ -7,7 +7,11 @@
 package simplelog
 
 import (
+	"context"
+	"errors"
 	"os"
+	"runtime"
+	"time"
 )
 
 // message catalog
@@ -17,6 +21,10 @@ const (
 	sg002 = "log service has not been started"
 	sg003 = "unknown log destination specified"
 	sg004 = "log file not setup"
+	sg005 = "log destination name is invalid or already in use"
+	sg006 = "unknown log destination name specified"
+	sg007 = "no more log destinations available"
+	sg008 = "colored output is only supported by STDOUT"
 )
 
 // SetPrefix sets the prefix for log records.
@@ -33,20 +41,77 @@ const (
 // In addition, to distinguish and parse date and time information, the reference time string has to be
 // delimited by # tags and can be used for example as follows: #2006-01-02 15:04:05.000000#.
 // Note that not all placeholders have to be used and they can be used in any order.
+// If the prefix should contain the level of the log record, the %LEVEL% placeholder can be used, e.g. [%LEVEL%].
 //
-// The destination specifies the name of the log destination where the prefix should be used, e.g. STDOUT or FILE.
+// The destination specifies the name of the log destination where the prefix should be used, e.g. STDOUT, FILE
+// or the log destination of a named log file.
 // The prefix specifies the prefix for each log record for a given log destination.
 func SetPrefix(destination int, prefix ...string) {
 	if s.isActive() {
-		switch destination {
-		case STDOUT:
-			s.configService <- configMessage{setprefix, map[int]any{stdoutlogprefix: prefix}}
-		case FILE:
-			s.configService <- configMessage{setprefix, map[int]any{filelogprefix: prefix}}
+		switch {
+		case destination == STDOUT:
+			s.configure(configMessage{setprefix, map[int]any{stdoutlogprefix: prefix}})
+		case s.isFileDestination(destination):
+			s.configure(configMessage{setprefix, map[int]any{filelogprefix: prefix, logdestination: destination}})
 		default:
 			panic(sg003)
 		}
-		<-s.configServiceResponse
+	} else {
+		panic(sg002)
+	}
+}
+
+// SetFormatter sets the formatter for log records.
+// By default, log records are formatted by the TextFormatter, which places the prefix of the log
+// destination (see SetPrefix) in front of the logged values.
+// The destination specifies the log destination where the formatter should be used, e.g. STDOUT, FILE
+// or the log destination of a named log file.
+// The formatter specifies the formatter for each log record for a given log destination; nil restores
+// the TextFormatter. A colored TextFormatter (see ColorMode) can only be used for STDOUT.
+func SetFormatter(destination int, formatter Formatter) {
+	if s.isActive() {
+		if destination != STDOUT && !s.isFileDestination(destination) {
+			panic(sg003)
+		}
+		if destination != STDOUT && isColored(formatter) {
+			panic(sg008)
+		}
+		s.configure(configMessage{setformatter, map[int]any{logdestination: destination, logformatter: formatter}})
+	} else {
+		panic(sg002)
+	}
+}
+
+// SetMaxRecordSize sets the maximum size of the log records written to a log destination. Longer log records,
+// e.g. of a logged response body, are truncated and marked by "…[truncated N bytes]", where N is the number of
+// bytes cut off, instead of flooding the log destination.
+// The destination parameter specifies the log destination: STDOUT, FILE or a named log file.
+// The maxSize parameter specifies the maximum size in bytes of a formatted log record, not counting the
+// trailing newline; 0 means no limit.
+func SetMaxRecordSize(destination int, maxSize int) {
+	if s.isActive() {
+		if destination != STDOUT && !s.isFileDestination(destination) {
+			panic(sg003)
+		}
+		if maxSize < 0 {
+			maxSize = 0
+		}
+		s.configure(configMessage{setmaxrecordsize, map[int]any{logdestination: destination, logmaxrecordsize: maxSize}})
+	} else {
+		panic(sg002)
+	}
+}
+
+// SetBatchSize sets the maximum number of pending log messages which are processed at once by the log service.
+// The log records of such a batch are collected per log destination and written with a single write, which
+// greatly reduces the number of system calls, especially for STDOUT. The default batch size is 128.
+// The batchSize specifies the maximum number of log messages of a batch; 1 disables batching.
+func SetBatchSize(batchSize int) {
+	if s.isActive() {
+		if batchSize < 1 {
+			batchSize = 1
+		}
+		s.configure(configMessage{setbatchsize, map[int]any{logbatchsize: batchSize}})
 	} else {
 		panic(sg002)
 	}
@@ -56,11 +121,11 @@ func SetPrefix(destination int, prefix ...string) {
 // Before the log service is stopped, all pending log messages are flushed and resources are released.
 // Archiving a log file means that it will be renamed and no new messages will be appended on a new run.
 // The archived log file is of the following format: <log file name>_yyyymmddHHMMSS.
-// The archivelog flag indicates whether the log file will be archived (true) or not (false).
+// The archivelog flag indicates whether the log files, including all named log files, will be archived (true) or not (false).
 func Shutdown(archivelog bool) {
-	if s.isActive() {
+	if s.deactivate() {
 		s.stop(archivelog)
-		s.setActive(false)
+		s.resetDestinations()
 	} else {
 		panic(sg000)
 	}
@@ -70,12 +135,56 @@ func Shutdown(archivelog bool) {
 // The log service runs in its own goroutine.
 // The bufferSize specifies the number of log messages which can be buffered before the log service blocks.
 func Startup(bufferSize int) {
+	startup(bufferSize, 0)
+}
+
+// StartupSharded starts the log service like Startup, but the log messages are passed to the log service
+// by a sharded queue instead of a single channel. This reduces the contention of many goroutines which
+// log concurrently. The log messages of each goroutine are written in the order they are logged; the log
+// messages of different goroutines are written in the order of their time within each written batch.
+// The bufferSize specifies the number of log messages which can be buffered before the log service blocks.
+// The shards specifies the number of shards; if it is less than 1, runtime.GOMAXPROCS(0) is used.
+func StartupSharded(bufferSize, shards int) {
+	if shards < 1 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	startup(bufferSize, shards)
+}
+
+// StartupSync starts the log service in synchronous mode, i.e. without a goroutine of its own.
+// Each write formats the log message in the caller's goroutine and returns after the log message is written
+// to its log destinations; concurrent writes are serialized by a mutex. Apart from that, the log service works
+// as if it was started by Startup, including prefixes, formatters, levels and rotation.
+// The log service has to be stopped by calling Shutdown.
+func StartupSync() {
 	if !s.isActive() {
-		s.dataQueue = make(chan logMessage, bufferSize)
+		s.dataQueue = nil
+		s.shardedQueue = nil
+		s.batchSize = defaultBatchSize
+		s.lastCheck = time.Now()
+		s.synchronous = true
+		s.setActive(true)
+	} else {
+		panic(sg001)
+	}
+}
+
+// startup starts the log service with a single channel, if shards is 0, or with a sharded queue otherwise.
+func startup(bufferSize, shards int) {
+	if !s.isActive() {
+		s.synchronous = false
+		s.dataQueue = nil
+		s.shardedQueue = nil
+		if shards > 0 {
+			s.shardedQueue = newShardedQueue(shards, bufferSize)
+		} else {
+			s.dataQueue = make(chan *logMessage, bufferSize)
+		}
 		s.configService = make(chan configMessage)
 		s.configServiceResponse = make(chan error)
 		s.stopService = make(chan bool)
 		s.stopServiceResponse = make(chan struct{})
+		s.batchSize = defaultBatchSize
 		serviceRunning := make(chan bool)
 
 		go s.run(serviceRunning)
@@ -102,8 +211,7 @@ func SetupLog(logName string, appendlog bool) {
 		} else {
 			flag = os.O_TRUNC | os.O_CREATE | os.O_WRONLY
 		}
-		s.configService <- configMessage{initlog, map[int]any{logflag: flag, logfilename: logName}}
-		if err := <-s.configServiceResponse; err != nil {
+		if err := s.configure(configMessage{initlog, map[int]any{logflag: flag, logfilename: logName}}); err != nil {
 			panic(err)
 		}
 	} else {
@@ -119,8 +227,107 @@ func SwitchLog(newLogName string) {
 	if s.isActive() {
 		var err error
 		flag := os.O_EXCL | os.O_CREATE | os.O_WRONLY
-		s.configService <- configMessage{switchlog, map[int]any{logflag: flag, logfilename: newLogName}}
-		if err = <-s.configServiceResponse; err != nil {
+		if err = s.configure(configMessage{switchlog, map[int]any{logflag: flag, logfilename: newLogName, logdestination: FILE}}); err != nil {
+			panic(err)
+		}
+	} else {
+		panic(sg002)
+	}
+}
+
+// Reopen closes all log files, including all named log files, and opens them again by their names.
+// This is needed if log files are rotated by an external tool like logrotate, which renames the log file
+// and creates a new one: without reopening, logging would continue into the renamed log file.
+// Before the log files are closed, all pending log messages are flushed. The log files are reopened in append
+// mode and created, if they don't exist. Unlike SwitchLog, the name of a log file doesn't change.
+func Reopen() {
+	if s.isActive() {
+		if err := reopen(); err != nil {
+			panic(err)
+		}
+	} else {
+		panic(sg002)
+	}
+}
+
+// reopen triggers the log service to reopen all log files and returns the first error which occurred.
+// If the log service isn't running or is stopped concurrently, an error is returned.
+func reopen() error {
+	if !s.isActive() {
+		return errors.New(sg000)
+	}
+	return s.configure(configMessage{reopenlog, nil})
+}
+
+// SetupLogNamed opens and initially creates an additional log file, which is identified by a name.
+// Any number of named log files can be set up beside the log file set up by SetupLog. Each of them has
+// its own prefix, rotation and archive settings.
+// The name parameter specifies the name which identifies the log file, e.g. "audit".
+// The logName parameter specifies the name of the log file.
+// With appendLog it is possible to specify, if a new run of the application first truncates the
+// old log before new log entries are written (false) or if new messages are appended to the already
+// existing log (true).
+// The returned log destination can be used like STDOUT or FILE and can also be combined with them, e.g.
+// STDOUT | audit.
+func SetupLogNamed(name, logName string, appendlog bool) int {
+	if s.isActive() {
+		var flag int
+		if appendlog {
+			flag = os.O_APPEND | os.O_CREATE | os.O_WRONLY
+		} else {
+			flag = os.O_TRUNC | os.O_CREATE | os.O_WRONLY
+		}
+		destination := s.registerDestination(name)
+		if err := s.configure(configMessage{initlog, map[int]any{logflag: flag, logfilename: logName, logdestination: destination}}); err != nil {
+			s.unregisterDestination(name)
+			panic(err)
+		}
+		return destination
+	} else {
+		panic(sg002)
+	}
+}
+
+// Destination returns the log destination of a named log file.
+// The name parameter specifies the name which was used to set up the log file by SetupLogNamed.
+func Destination(name string) int {
+	destination, ok := s.destination(name)
+	if !ok {
+		panic(sg006)
+	}
+	return destination
+}
+
+// SwitchLogNamed closes the current log file of a named log file and a new log file with the specified
+// name is created and used. It works like SwitchLog, but for a named log file.
+// The name parameter specifies the name which was used to set up the log file by SetupLogNamed.
+// The newLogName specifies the name of the new log to switch to.
+func SwitchLogNamed(name, newLogName string) {
+	if s.isActive() {
+		destination := Destination(name)
+		if !s.isFileDestination(destination) {
+			panic(sg003)
+		}
+		var err error
+		flag := os.O_EXCL | os.O_CREATE | os.O_WRONLY
+		if err = s.configure(configMessage{switchlog, map[int]any{logflag: flag, logfilename: newLogName, logdestination: destination}}); err != nil {
+			panic(err)
+		}
+	} else {
+		panic(sg002)
+	}
+}
+
+// CloseLog closes a named log file while the log service keeps running.
+// Before the log file is closed, all pending log messages are flushed.
+// The name parameter specifies the name which was used to set up the log file by SetupLogNamed.
+// The archivelog flag indicates whether the log file will be archived (true) or not (false).
+func CloseLog(name string, archivelog bool) {
+	if s.isActive() {
+		destination := Destination(name)
+		err := s.configure(configMessage{closelog, map[int]any{logdestination: destination, logarchive: archivelog}})
+		s.unregisterDestination(name)
+		if err != nil {
 			panic(err)
 		}
 	} else {
@@ -128,21 +335,166 @@ func SwitchLog(newLogName string) {
 	}
 }
 
+// SetRotation sets the size at which a log file is rotated.
+// Rotating a log file means that it is archived (see Shutdown) and that logging continues with a new,
+// empty log file of the same name.
+// The destination specifies the log destination of the log file, i.e. FILE or the log destination of a named log file.
+// The maxSize specifies the size in bytes at which the log file is rotated; 0 disables the rotation.
+func SetRotation(destination int, maxSize int64) {
+	if s.isActive() {
+		if !s.isFileDestination(destination) {
+			panic(sg003)
+		}
+		s.configure(configMessage{setrotation, map[int]any{logdestination: destination, logmaxsize: maxSize}})
+	} else {
+		panic(sg002)
+	}
+}
+
 // Write writes a log message to a specified destination.
+// The log message is written with level INFO.
 // The destination parameter specifies the log destination, where the data will be written to.
+// Log destinations can be combined, e.g. STDOUT | FILE, which is the same as MULTI.
 // The logValues parameter consists of one or multiple values that are logged.
+// Values which are expensive to compute can be passed as LogValuer or as func() any; they are evaluated lazily
+// by the log service and only if at least one log destination accepts the log message.
 func Write(destination int, values ...any) {
+	write(nil, nil, INFO, destination, "", values, "")
+}
+
+// WriteLevel writes a log message with a given level to a specified destination.
+// The log message is only written to log destinations whose level (see SetLevel) is lower than or equal to the given level.
+// The level parameter specifies the level of the log message, e.g. DEBUG or ERROR.
+// The destination parameter specifies the log destination, where the data will be written to.
+// The logValues parameter consists of one or multiple values that are logged.
+func WriteLevel(level Level, destination int, values ...any) {
+	write(nil, nil, level, destination, "", values, "")
+}
+
+// Writef writes a log message, which is formatted according to a format specifier, to a specified destination.
+// The log message is written with level INFO and is formatted as with fmt.Sprintf by the log service, i.e. not in
+// the calling goroutine and only if at least one log destination accepts the log message.
+// The destination parameter specifies the log destination, where the data will be written to.
+// The format parameter specifies the format specifier, e.g. "%d records in %v".
+// The logValues parameter consists of the values referenced by the format specifier.
+func Writef(destination int, format string, values ...any) {
+	write(nil, nil, INFO, destination, format, values, "")
+}
+
+// WriteStack writes a log message together with the stack trace of the calling goroutine to a specified destination.
+// The log message is written with level INFO.
+// The destination parameter specifies the log destination, where the data will be written to.
+// The logValues parameter consists of one or multiple values that are logged.
+func WriteStack(destination int, values ...any) {
+	write(nil, nil, INFO, destination, "", values, captureStack(1))
+}
+
+// SetStackTrace enables (true) or disables (false) that the stack trace of the calling goroutine is attached
+// automatically to every log record with level ERROR. By default, it is disabled.
+func SetStackTrace(enabled bool) {
+	s.setStackTrace(enabled)
+}
+
+// write sends a log message to the log service.
+// The values are copied into the pooled log message, hence they don't escape to the heap.
+// If a logger is given, its component name and bound fields are added to the log message.
+// If a context is given, the fields stored in it are added to the log message and the log message is dropped,
+// if the context is done while the buffer is full. Log messages below the level override of the call site
+// (see SetLevelOverrides) are dropped right away.
+// If no stack trace is given, but stack traces are enabled for the level of the log message (see SetStackTrace),
+// the stack trace of the caller of the exported function calling write is attached.
+func write(ctx context.Context, l *Logger, level Level, destination int, format string, values []any, stack string) {
+	logMsg := newLogMessage(level, destination)
+	if minLevel, ok := s.callSiteLevel(l); ok {
+		if level < minLevel {
+			// dropped by a level override
+			releaseLogMessage(logMsg)
+			return
+		}
+		logMsg.override = true
+	}
+	logMsg.Format = format
+	logMsg.Values = append(logMsg.Values, values...)
+	if l != nil {
+		logMsg.Component = l.component
+		logMsg.Fields = append(logMsg.Fields, l.fields...)
+	}
+	if ctx != nil {
+		logMsg.Fields = append(logMsg.Fields, contextFields(ctx)...)
+	}
+	logMsg.Stack = stack
+	if stack == "" && level >= ERROR && s.isStackTrace() {
+		logMsg.Stack = captureStack(2)
+	}
+	send(ctx, l, logMsg)
+}
+
+// Log writes a log message with a given level and typed fields to a specified destination.
+// Unlike the other write functions, Log doesn't allocate memory, as long as the fields are created by
+// the typed field constructors like String or Int.
+// The level parameter specifies the level of the log message, e.g. DEBUG or ERROR.
+// The destination parameter specifies the log destination, where the data will be written to.
+// The msg parameter specifies the message that is logged.
+// The fields parameter consists of zero or more fields that are logged as key/value pairs.
+func Log(level Level, destination int, msg string, fields ...Field) {
+	log(nil, nil, level, destination, msg, fields)
+}
+
+// log sends a log message with typed fields to the log service.
+// If a logger is given, its component name is added to the log message and its bound fields are added
+// in front of the given fields. If a context is given, the fields stored in it are added in front of the given
+// fields as well and the log message is dropped, if the context is done while the buffer is full.
+// If stack traces are enabled for the level of the log message (see SetStackTrace), the stack trace of the
+// caller of the exported function calling log is attached.
+func log(ctx context.Context, l *Logger, level Level, destination int, msg string, fields []Field) {
+	logMsg := newLogMessage(level, destination)
+	if minLevel, ok := s.callSiteLevel(l); ok {
+		if level < minLevel {
+			// dropped by a level override
+			releaseLogMessage(logMsg)
+			return
+		}
+		logMsg.override = true
+	}
+	logMsg.Msg = msg
+	if l != nil {
+		logMsg.Component = l.component
+		logMsg.Fields = append(logMsg.Fields, l.fields...)
+	}
+	if ctx != nil {
+		logMsg.Fields = append(logMsg.Fields, contextFields(ctx)...)
+	}
+	logMsg.Fields = append(logMsg.Fields, fields...)
+	if level >= ERROR && s.isStackTrace() {
+		logMsg.Stack = captureStack(2)
+	}
+	send(ctx, l, logMsg)
+}
+
+// send sends a log message to the log service, unless it is kept by the flight recorder of the logger or the context.
+// If a context is given, the log message is dropped, if the context is done while the buffer is full.
+func send(ctx context.Context, l *Logger, logMsg *logMessage) {
+	if r := recorderOf(ctx, l); r != nil && r.record(logMsg) {
+		return
+	}
+	if ctx != nil {
+		s.enqueueCtx(ctx, logMsg)
+	} else {
+		s.enqueue(logMsg)
+	}
+}
+
+// SetLevel sets the minimum level of log records for a log destination.
+// Log records with a lower level are not written to the log destination. The default level is INFO.
+// The destination specifies the log destination where the level should be used, e.g. STDOUT, FILE,
+// the log destination of a named log file or of a ring buffer.
+// The level specifies the minimum level of log records for a given log destination.
+func SetLevel(destination int, level Level) {
 	if s.isActive() {
-		switch destination {
-		case STDOUT:
-			s.dataQueue <- logMessage{STDOUT, values}
-		case FILE:
-			s.dataQueue <- logMessage{FILE, values}
-		case MULTI:
-			s.dataQueue <- logMessage{MULTI, values}
-		default:
+		if destination != STDOUT && !s.isFileDestination(destination) && !s.isRingDestination(destination) {
 			panic(sg003)
 		}
+		s.configure(configMessage{setlevel, map[int]any{logdestination: destination, loglevel: level}})
 	} else {
 		panic(sg002)
 	}
@@ -155,16 +507,7 @@ func Write(destination int, values ...any) {
 func ConditionalWrite(condition bool, destination int, values ...any) {
 	if s.isActive() {
 		if condition {
-			switch destination {
-			case STDOUT:
-				s.dataQueue <- logMessage{STDOUT, values}
-			case FILE:
-				s.dataQueue <- logMessage{FILE, values}
-			case MULTI:
-				s.dataQueue <- logMessage{MULTI, values}
-			default:
-				panic(sg003)
-			}
+			write(nil, nil, INFO, destination, "", values, "")
 		}
 	} else {
 		panic(sg002)
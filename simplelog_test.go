@@ -1,11 +1,19 @@
 package simplelog
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestStartup(t *testing.T) {
@@ -224,6 +232,1162 @@ func TestLogToMulti(t *testing.T) {
 	}
 }
 
+func TestLogToNamedFiles(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+	auditFile := "audit.log"
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+	if _, err := os.Stat(auditFile); err == nil {
+		os.Remove(auditFile)
+	}
+
+	Startup(1)
+	SetupLog(logFile, false)
+	audit := SetupLogNamed("audit", auditFile, false)
+	SetPrefix(audit, "[AUDIT]")
+	Write(FILE, "application record")
+	Write(audit, "audit record")
+	Write(FILE|Destination("audit"), "shared record")
+	Shutdown(false)
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Error("Expected to find file", logFile, "- but got:", err)
+	} else if strings.Contains(string(data), "audit record") || !strings.Contains(string(data), "shared record") {
+		t.Error("Expected log records of", logFile, "only - but got:", string(data))
+	} else {
+		os.Remove(logFile)
+	}
+
+	data, err = os.ReadFile(auditFile)
+	if err != nil {
+		t.Error("Expected to find file", auditFile, "- but got:", err)
+	} else if strings.Contains(string(data), "application record") || !strings.Contains(string(data), "[AUDIT] shared record") {
+		t.Error("Expected log records of", auditFile, "only - but got:", string(data))
+	} else {
+		os.Remove(auditFile)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+
+	Startup(1)
+	SetupLog(logFile, false)
+	SetRotation(FILE, 10)
+	Write(FILE, "The answer to all questions is", 42)
+	Write(FILE, "The answer to all questions is", 43)
+	Shutdown(false)
+
+	archives, _ := filepath.Glob(logFile + "_*")
+	if len(archives) != 2 {
+		t.Error("Expected 2 archived log files - but found:", archives)
+	}
+	for _, archive := range archives {
+		os.Remove(archive)
+	}
+	os.Remove(logFile)
+}
+
+type upperFormatter struct{}
+
+func (upperFormatter) Format(buf []byte, rec *Record) []byte {
+	return append(buf, strings.ToUpper(rec.Message())+"\n"...)
+}
+
+func TestSetFormatter(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+	auditFile := "audit.log"
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+	if _, err := os.Stat(auditFile); err == nil {
+		os.Remove(auditFile)
+	}
+
+	Startup(1)
+	SetupLog(logFile, false)
+	audit := SetupLogNamed("audit", auditFile, false)
+	SetFormatter(FILE, JSONFormatter{})
+	SetFormatter(audit, upperFormatter{})
+	Write(FILE|audit, "The answer to all questions is", 42)
+	Shutdown(false)
+
+	expected := `"msg":"The answer to all questions is 42"}`
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Error("Expected to find file", logFile, "- but got:", err)
+	} else if !strings.Contains(string(data), expected) {
+		t.Error("Expected log record contains:", expected, "- but it doesn't:", string(data))
+	} else {
+		os.Remove(logFile)
+	}
+
+	expected = "THE ANSWER TO ALL QUESTIONS IS 42"
+	data, err = os.ReadFile(auditFile)
+	if err != nil {
+		t.Error("Expected to find file", auditFile, "- but got:", err)
+	} else if !strings.Contains(string(data), expected) {
+		t.Error("Expected log record contains:", expected, "- but it doesn't:", string(data))
+	} else {
+		os.Remove(auditFile)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "simplelog.json")
+	os.WriteFile(configFile, []byte(`{"bufferSize": 10, "file": {"path": "test1.log", "level": "debug"}, "files": {"audit": {"path": "audit.log"}}}`), 0644)
+	t.Setenv("SIMPLELOG_BUFFER_SIZE", "20")
+	t.Setenv("SIMPLELOG_FILES_AUDIT_FORMAT", "json")
+	t.Setenv("SIMPLELOG_STDOUT_PREFIX", "#15:04:05#|[Test]")
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatal("Expected a valid configuration - but got:", err)
+	}
+	if cfg.BufferSize != 20 {
+		t.Error("Expected buffer size", 20, "but got:", cfg.BufferSize)
+	}
+	if cfg.File.Level != "debug" {
+		t.Error("Expected file level debug but got:", cfg.File.Level)
+	}
+	if cfg.Files["audit"].Format != "json" {
+		t.Error("Expected audit format json but got:", cfg.Files["audit"].Format)
+	}
+	if strings.Join(cfg.Stdout.Prefix, " ") != "#15:04:05# [Test]" {
+		t.Error("Expected stdout prefix #15:04:05# [Test] but got:", cfg.Stdout.Prefix)
+	}
+
+	t.Setenv("SIMPLELOG_BUFFER_SIZE", "-1")
+	t.Setenv("SIMPLELOG_FILE_LEVEL", "verbose")
+	t.Setenv("SIMPLELOG_FILES_AUDIT_PATH", "test1.log")
+	_, err = LoadConfig(configFile)
+	if cfgErr, ok := err.(*ConfigError); !ok || len(cfgErr.Problems) != 3 {
+		t.Error("Expected 3 configuration problems - but got:", err)
+	}
+}
+
+func TestStartupConfig(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+
+	StartupConfig(&Config{BufferSize: 1, File: &FileConfig{DestinationConfig: DestinationConfig{Level: "warn", Format: "logfmt"}, Path: logFile}})
+	Write(FILE, "The answer to all questions is", 42)
+	WriteLevel(ERROR, FILE, "The answer to all questions is", 43)
+	Shutdown(false)
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Error("Expected to find file", logFile, "- but got:", err)
+	} else if strings.Contains(string(data), "is 42") || !strings.Contains(string(data), `level=ERROR msg="The answer to all questions is 43"`) {
+		t.Error("Expected the ERROR log record only - but got:", string(data))
+	} else {
+		os.Remove(logFile)
+	}
+}
+
+func TestReloadOnSignal(t *testing.T) {
+	if len(reloadSignals) == 0 {
+		t.Skip("no default reload signal on this platform")
+	}
+	s = new(simpleLogService) // reset service instance
+	logFile1 := "test1.log"
+	logFile2 := "test2.log"
+	configFile := filepath.Join(t.TempDir(), "simplelog.json")
+
+	if _, err := os.Stat(logFile1); err == nil {
+		os.Remove(logFile1)
+	}
+	if _, err := os.Stat(logFile2); err == nil {
+		os.Remove(logFile2)
+	}
+
+	os.WriteFile(configFile, []byte(`{"file": {"path": "`+logFile1+`"}}`), 0644)
+	cfg, _ := LoadConfig(configFile)
+	StartupConfig(cfg)
+	stop := ReloadOnSignal(configFile, func(err error) { t.Error("Expected a successful reload - but got:", err) })
+	defer stop()
+	Write(FILE, "record", 1)
+
+	// a failed reload keeps the current configuration
+	if err := ApplyConfig(&Config{File: &FileConfig{Path: filepath.Join(configFile, "test.log")}}); err == nil {
+		t.Error("Expected an error for an invalid log file path")
+	}
+	Write(FILE, "record", 2)
+
+	os.WriteFile(configFile, []byte(`{"file": {"path": "`+logFile2+`", "prefix": ["[%LEVEL%]"]}}`), 0644)
+	p, _ := os.FindProcess(os.Getpid())
+	p.Signal(reloadSignals[0])
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(logFile2); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	Write(FILE, "record", 3)
+
+	// a configuration which leaves out the log file keeps it
+	if err := ApplyConfig(&Config{}); err != nil {
+		t.Error("Expected no error - but got:", err)
+	}
+	Write(MULTI, "record", 4)
+	Shutdown(false)
+
+	// a reload by a signal racing with Shutdown fails instead of panicking
+	if err := reload(configFile); err == nil || err.Error() != sg000 {
+		t.Error("Expected the reload to fail after Shutdown - but got:", err)
+	}
+
+	data, err := os.ReadFile(logFile1)
+	if err != nil {
+		t.Error("Expected to find file", logFile1, "- but got:", err)
+	} else if !strings.Contains(string(data), "record 1\nrecord 2\n") {
+		t.Error("Expected log records 1 and 2 - but got:", string(data))
+	} else {
+		os.Remove(logFile1)
+	}
+	data, err = os.ReadFile(logFile2)
+	if err != nil {
+		t.Error("Expected to find file", logFile2, "- but got:", err)
+	} else if string(data) != "\n[INFO] record 3\n[INFO] record 4\n" {
+		t.Error("Expected log records 3 and 4 - but got:", string(data))
+	} else {
+		os.Remove(logFile2)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+	rotatedFile := "test1.log.1"
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+
+	Startup(1)
+	SetupLog(logFile, false)
+	Write(FILE, "record", 1)
+	Reopen() // flushes record 1
+	os.Rename(logFile, rotatedFile)
+	Reopen()
+	Write(FILE, "record", 2)
+	Shutdown(false)
+
+	data, err := os.ReadFile(rotatedFile)
+	if err != nil {
+		t.Error("Expected to find file", rotatedFile, "- but got:", err)
+	} else if !strings.Contains(string(data), "record 1") || strings.Contains(string(data), "record 2") {
+		t.Error("Expected log record 1 only - but got:", string(data))
+	} else {
+		os.Remove(rotatedFile)
+	}
+	data, err = os.ReadFile(logFile)
+	if err != nil {
+		t.Error("Expected to find file", logFile, "- but got:", err)
+	} else if !strings.Contains(string(data), "record 2") || strings.Contains(string(data), "record 1") {
+		t.Error("Expected log record 2 only - but got:", string(data))
+	} else {
+		os.Remove(logFile)
+	}
+
+	// a log file opened by ApplyConfig is reopened for writing as well
+	s = new(simpleLogService) // reset service instance
+	Startup(1)
+	if err = ApplyConfig(&Config{File: &FileConfig{Path: logFile}}); err != nil {
+		t.Fatal("Expected no error - but got:", err)
+	}
+	Write(FILE, "before")
+	Reopen()
+	Write(FILE, "after")
+	Shutdown(false)
+
+	// a reopen by a signal racing with Shutdown fails instead of panicking
+	if err := reopen(); err == nil || err.Error() != sg000 {
+		t.Error("Expected the reopen to fail after Shutdown - but got:", err)
+	}
+
+	data, err = os.ReadFile(logFile)
+	if err != nil {
+		t.Error("Expected to find file", logFile, "- but got:", err)
+	} else if !strings.Contains(string(data), "before\n") || !strings.Contains(string(data), "after\n") {
+		t.Error("Expected the log records before and after reopening - but got:", string(data))
+	} else {
+		os.Remove(logFile)
+	}
+}
+
+func TestRecreateRemovedLogFile(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+	expected := "log file " + logFile + " was moved or removed and has been recreated"
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+
+	Startup(1)
+	SetupLog(logFile, false)
+	Write(FILE, "record", 1)
+	os.Remove(logFile)
+	for i := 0; i < 300; i++ {
+		if _, err := os.Stat(logFile); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	Write(FILE, "record", 2)
+	Shutdown(false)
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Error("Expected to find file", logFile, "- but got:", err)
+	} else if !strings.Contains(string(data), expected+"\nrecord 2") {
+		t.Error("Expected log records:", expected, "and record 2 - but got:", string(data))
+	} else {
+		os.Remove(logFile)
+	}
+}
+
+func TestFatalAndPanic(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+	exitCode := 0
+	osExit = func(code int) { exitCode = code }
+	defer func() { osExit = os.Exit }()
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+
+	Startup(10)
+	SetupLog(logFile, false)
+	Fatal(FILE, "fatal record")
+	if exitCode != 1 {
+		t.Error("Expected exit code", 1, "but got:", exitCode)
+	}
+	// the log record must be written before the program exits
+	if data, _ := os.ReadFile(logFile); !strings.Contains(string(data), "fatal record") {
+		t.Error("Expected log record: fatal record - but got:", string(data))
+	}
+
+	calls := 0
+	func() {
+		defer func() {
+			if v := recover(); v != "panic record 42" {
+				t.Error("Expected panic value: panic record 42 - but got:", v)
+			}
+		}()
+		defer RecoverAndLog(FILE)
+		Panic(FILE, "panic record", func() any { calls++; return 42 })
+	}()
+	if calls != 1 {
+		t.Error("Expected the lazy value to be evaluated once - but got:", calls)
+	}
+	Shutdown(false)
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Error("Expected to find file", logFile, "- but got:", err)
+	} else if !strings.Contains(string(data), "panic record 42\npanic: panic record 42") || !strings.Contains(string(data), "TestFatalAndPanic") {
+		t.Error("Expected log records of the panic including the stack trace - but got:", string(data))
+	} else {
+		os.Remove(logFile)
+	}
+}
+
+func TestWriteStack(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+	auditFile := "audit.log"
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+	if _, err := os.Stat(auditFile); err == nil {
+		os.Remove(auditFile)
+	}
+
+	Startup(1)
+	SetupLog(logFile, false)
+	audit := SetupLogNamed("audit", auditFile, false)
+	SetFormatter(audit, JSONFormatter{})
+	WriteStack(FILE, "stack record")
+	WriteLevel(ERROR, audit, "error record without stack")
+	SetStackTrace(true)
+	WriteLevel(ERROR, audit, "error record with stack")
+	Shutdown(false)
+
+	expected := "stack record\n\tgithub.com/sabitor/simplelog.TestWriteStack()\n\t\t"
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Error("Expected to find file", logFile, "- but got:", err)
+	} else if !strings.Contains(string(data), expected) {
+		t.Error("Expected log record contains:", expected, "- but it doesn't:", string(data))
+	} else {
+		os.Remove(logFile)
+	}
+
+	data, _ = os.ReadFile(auditFile)
+	lines := strings.Split(string(data), "\n")
+	if len(lines) != 4 || strings.Contains(lines[1], `"stack"`) || !strings.Contains(lines[2], `"stack":"github.com/sabitor/simplelog.TestWriteStack()\n\t`) {
+		t.Error("Expected the second log record only contains a stack - but got:", lines)
+	} else {
+		os.Remove(auditFile)
+	}
+}
+
+type lazyValue struct {
+	evaluated *int
+}
+
+func (v lazyValue) LogValue() any {
+	*v.evaluated++
+	return "lazy"
+}
+
+func TestWritefAndLazyValues(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+	evaluated := 0
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+
+	Startup(1)
+	SetupLog(logFile, false)
+	SetLevel(FILE, WARN)
+	Write(FILE, lazyValue{&evaluated}, func() any { evaluated++; return "func" })
+	WriteLevel(WARN, FILE, lazyValue{&evaluated}, func() any { evaluated++; return "func" })
+	Writef(FILE, "%d records in %s", 42, "test1.log")
+	SetLevel(FILE, INFO)
+	Writef(FILE, "%d records in %v", 42, lazyValue{&evaluated})
+	Shutdown(false)
+
+	if evaluated != 3 {
+		t.Error("Expected", 3, "evaluated lazy values but got:", evaluated)
+	}
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Error("Expected to find file", logFile, "- but got:", err)
+	} else if string(data) != "\nlazy func\n42 records in lazy\n" {
+		t.Error("Expected log records: lazy func and 42 records in lazy - but got:", string(data))
+	} else {
+		os.Remove(logFile)
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+	auditFile := "audit.log"
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+	if _, err := os.Stat(auditFile); err == nil {
+		os.Remove(auditFile)
+	}
+
+	Startup(1)
+	SetupLog(logFile, false)
+	audit := SetupLogNamed("audit", auditFile, false)
+	SetFormatter(audit, JSONFormatter{})
+	fields := []Field{String("user", "John Doe"), Int("id", 42), Float64("ratio", 0.5), Bool("ok", true), Duration("took", 1500*time.Millisecond), Err(io.EOF)}
+	Log(WARN, FILE|audit, "request done", fields...)
+	Log(INFO, FILE, "times", Time("unset", time.Time{}), Time("far", time.Date(3000, 1, 2, 3, 4, 5, 6, time.UTC)),
+		Time("now", time.Date(2024, 5, 17, 10, 30, 0, 0, time.FixedZone("CEST", 2*3600))))
+	Shutdown(false)
+
+	expected := `request done user="John Doe" id=42 ratio=0.5 ok=true took=1.5s error=EOF` + "\n" +
+		`times unset=0001-01-01T00:00:00Z far=3000-01-02T03:04:05.000000006Z now=2024-05-17T10:30:00+02:00`
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Error("Expected to find file", logFile, "- but got:", err)
+	} else if !strings.Contains(string(data), expected) {
+		t.Error("Expected log record contains:", expected, "- but it doesn't:", string(data))
+	} else {
+		os.Remove(logFile)
+	}
+
+	expected = `"level":"WARN","msg":"request done","user":"John Doe","id":42,"ratio":0.5,"ok":true,"took":"1.5s","error":"EOF"}`
+	data, err = os.ReadFile(auditFile)
+	if err != nil {
+		t.Error("Expected to find file", auditFile, "- but got:", err)
+	} else if !strings.Contains(string(data), expected) {
+		t.Error("Expected log record contains:", expected, "- but it doesn't:", string(data))
+	} else {
+		os.Remove(auditFile)
+	}
+}
+
+type blockingWriter struct {
+	writes  int
+	data    []byte
+	blocked chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	if w.writes == 0 {
+		close(w.blocked)
+		<-w.release
+	}
+	w.writes++
+	w.data = append(w.data, p...)
+	return len(p), nil
+}
+
+func TestBatchedWrites(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	w := &blockingWriter{blocked: make(chan struct{}), release: make(chan struct{})}
+	s.stdoutLogger.self = newLogger(w)
+
+	Startup(10)
+	Write(STDOUT, "record", 0) // blocks the log service until released
+	<-w.blocked
+	for i := 1; i <= 10; i++ {
+		Write(STDOUT, "record", i)
+	}
+	close(w.release)
+	Shutdown(false)
+
+	if w.writes != 2 {
+		t.Error("Expected", 2, "writes but got:", w.writes)
+	}
+	if !strings.HasSuffix(string(w.data), "record 9\nrecord 10\n") {
+		t.Error("Expected all log records - but got:", string(w.data))
+	}
+}
+
+func TestShardedQueue(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	var data strings.Builder
+	s.stdoutLogger.self = newLogger(&data)
+
+	StartupSharded(8, 4)
+	done := make(chan struct{})
+	for g := 0; g < 8; g++ {
+		go func(g int) {
+			for i := 0; i < 1000; i++ {
+				Write(STDOUT, g, i)
+			}
+			done <- struct{}{}
+		}(g)
+	}
+	for g := 0; g < 8; g++ {
+		<-done
+	}
+	Shutdown(false)
+
+	next := make([]int, 8)
+	for _, line := range strings.Split(strings.TrimSuffix(data.String(), "\n"), "\n") {
+		var g, i int
+		if _, err := fmt.Sscan(line, &g, &i); err != nil || g < 0 || g >= 8 {
+			t.Fatal("Unexpected log record:", line)
+		}
+		if i != next[g] {
+			t.Fatal("Expected log record", i, "of goroutine", g, "to be", next[g])
+		}
+		next[g]++
+	}
+	for g, n := range next {
+		if n != 1000 {
+			t.Error("Expected", 1000, "log records of goroutine", g, "but got:", n)
+		}
+	}
+
+	// a producer switching shards keeps its order and a single producer can fill all shards
+	q := newShardedQueue(4, 8)
+	t0 := time.Now()
+	var popped []*logMessage
+	for i := 0; i < 12; i++ {
+		if !q.pushAny(i%3, &logMessage{Record: Record{Time: t0.Add(time.Duration(i))}}) {
+			t.Fatal("Expected the queue to take log message", i)
+		}
+		if i == 7 {
+			if q.len() != 8 || q.tryPush(new(logMessage)) {
+				t.Fatal("Expected the queue to be full")
+			}
+			popped = q.popBatch(popped)
+		}
+	}
+	popped = q.popBatch(popped)
+	for i, logMsg := range popped {
+		if !logMsg.Time.Equal(t0.Add(time.Duration(i))) {
+			t.Fatal("Expected log message", i, "to be popped in order")
+		}
+	}
+	if len(popped) != 12 || q.len() != 0 {
+		t.Error("Expected all log messages to be popped - but got:", len(popped))
+	}
+}
+
+func TestStartupSync(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+
+	StartupSync()
+	SetupLog(logFile, false)
+	SetPrefix(FILE, "[%LEVEL%]")
+	Write(FILE, "The answer to all questions is", 42)
+
+	// the log record is written before Write returns
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal("Expected to find file", logFile, "- but got:", err)
+	}
+	if !strings.Contains(string(data), "[INFO] The answer to all questions is 42") {
+		t.Error("Expected log record contains:", "[INFO] The answer to all questions is 42", "- but it doesn't:", string(data))
+	}
+	logMsg := newLogMessage(INFO, FILE)
+	logMsg.Values = append(logMsg.Values, "racing record")
+	Shutdown(false)
+
+	// a log message racing with Shutdown is discarded after the log files were released
+	s.writeSync(logMsg)
+	if data, _ = os.ReadFile(logFile); strings.Contains(string(data), "racing record") {
+		t.Error("Expected the log message written after Shutdown to be discarded - but got:", string(data))
+	}
+
+	// the log service can be started in service mode again
+	Startup(1)
+	SetupLog(logFile, true)
+	Write(FILE, "service mode")
+	Shutdown(false)
+
+	if data, _ = os.ReadFile(logFile); !strings.Contains(string(data), "service mode") {
+		t.Error("Expected log record contains:", "service mode", "- but it doesn't:", string(data))
+	} else {
+		os.Remove(logFile)
+	}
+}
+
+func TestSubLoggers(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	var data strings.Builder
+	s.stdoutLogger.self = newLogger(&data)
+
+	StartupSync()
+	parent := Named("http").With(String("remote", "10.0.0.1"))
+	parent.Named("auth").With(Int("user", 42)).Write(STDOUT, "login")
+	parent.With(Bool("tls", true)).Log(WARN, STDOUT, "slow request", Duration("took", time.Second))
+	parent.Writef(STDOUT, "%d requests", 3)
+	SetFormatter(STDOUT, JSONFormatter{})
+	parent.Write(STDOUT, "done")
+	Shutdown(false)
+
+	expected := []string{
+		"[http.auth] login remote=10.0.0.1 user=42\n",
+		"[http] slow request remote=10.0.0.1 tls=true took=1s\n",
+		"[http] 3 requests remote=10.0.0.1\n",
+		`"level":"INFO","component":"http","msg":"done","remote":"10.0.0.1"}`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(data.String(), e) {
+			t.Error("Expected log records contain:", e, "- but they don't:", data.String())
+		}
+	}
+}
+
+func TestWriteCtx(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	var data strings.Builder
+	s.stdoutLogger.self = newLogger(&data)
+
+	StartupSync()
+	ctx := NewContext(context.Background(), String("request", "r1"))
+	WriteCtx(NewContext(ctx, String("tenant", "t1")), STDOUT, "handled")
+	Named("http").LogCtx(ctx, WARN, STDOUT, "slow", Int("status", 200))
+	Shutdown(false)
+
+	for _, e := range []string{"handled request=r1 tenant=t1\n", "[http] slow request=r1 status=200\n"} {
+		if !strings.Contains(data.String(), e) {
+			t.Error("Expected log records contain:", e, "- but they don't:", data.String())
+		}
+	}
+
+	// writes with a cancelled context give up if the buffer is full
+	for _, shards := range []int{0, 2} {
+		s = new(simpleLogService) // reset service instance
+		w := &blockingWriter{blocked: make(chan struct{}), release: make(chan struct{})}
+		s.stdoutLogger.self = newLogger(w)
+
+		startup(2, shards)
+		Write(STDOUT, "blocking") // blocks the log service until released
+		<-w.blocked
+		Write(STDOUT, "buffered")
+		Write(STDOUT, "buffered")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		WriteCtx(ctx, STDOUT, "dropped")
+		cancel()
+		close(w.release)
+		Shutdown(false)
+
+		if strings.Contains(string(w.data), "dropped") || strings.Count(string(w.data), "buffered") != 2 {
+			t.Error("Expected the log record of the cancelled context to be dropped - but got:", string(w.data))
+		}
+	}
+}
+
+func TestLevelOverrides(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	var data strings.Builder
+	s.stdoutLogger.self = newLogger(&data)
+
+	logFile := "test1.log"
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+
+	StartupSync()
+	SetupLog(logFile, false)
+	SetLevel(STDOUT, WARN)
+	SetLevel(FILE, ERROR)
+	if err := SetLevelOverrides("http*=debug,db=loud"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+	for _, spec := range []string{"http*=debug", "simplelog_test=trace", ""} {
+		if err := SetLevelOverrides(spec); err != nil {
+			t.Fatal("Expected no error - but got:", err)
+		}
+		Named("http.server").WriteLevel(DEBUG, STDOUT, spec, "http debug")
+		Named("db").WriteLevel(DEBUG, STDOUT, spec, "db debug")
+		WriteLevel(TRACE, STDOUT, spec, "trace")
+		Named("http").Write(STDOUT, spec, "http info")
+		WriteLevel(WARN, STDOUT, spec, "warn")
+		Named("http").WriteLevel(DEBUG, FILE, spec, "http debug")
+	}
+
+	// dynamic component names don't grow the cache of the call sites
+	if err := SetLevelOverrides("worker-7=debug"); err != nil {
+		t.Fatal("Expected no error - but got:", err)
+	}
+	for i := 0; i < 1000; i++ {
+		Named("worker-"+strconv.Itoa(i)).WriteLevel(DEBUG, STDOUT, "worker", i)
+	}
+	if o := s.levelOverrides.Load().(*levelOverrides); len(o.sites) != 1 {
+		t.Error("Expected a single cached call site - but got:", len(o.sites))
+	}
+	Shutdown(false)
+
+	if data, _ := os.ReadFile(logFile); strings.Count(string(data), "http debug") != 2 {
+		t.Error("Expected log records of the level overrides in the log file - but got:", string(data))
+	} else {
+		os.Remove(logFile)
+	}
+
+	expected := "[http.server] http*=debug http debug\n[http] http*=debug http info\nhttp*=debug warn\n" +
+		"[http.server] simplelog_test=trace http debug\n[db] simplelog_test=trace db debug\nsimplelog_test=trace trace\n" +
+		"[http] simplelog_test=trace http info\nsimplelog_test=trace warn\n" +
+		" warn\n[worker-7] worker 7\n"
+	if data.String() != expected {
+		t.Error("Expected log records:", expected, "- but got:", data.String())
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+
+	Startup(10)
+	SetupLog(logFile, false)
+	server := httptest.NewServer(AdminHandler())
+	request := func(method, path, body string) (int, string) {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("Expected no error - but got:", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		contains           string
+	}{
+		{http.MethodGet, "/", "", http.StatusOK, `"name":"file","level":"INFO","prefix":null,"format":"text","file":"test1.log"`},
+		{http.MethodPut, "/level?destination=file&level=debug", "", http.StatusOK, `"name":"file","level":"DEBUG"`},
+		{http.MethodPut, "/level?destination=audit&level=debug", "", http.StatusBadRequest, sg003},
+		{http.MethodPut, "/prefix?destination=stdout", `["[%LEVEL%]"]`, http.StatusOK, `"name":"stdout","level":"INFO","prefix":["[%LEVEL%]"]`},
+		{http.MethodPut, "/overrides", "http*=debug", http.StatusOK, `"overrides":"http*=debug"`},
+		{http.MethodPut, "/overrides", "http*=loud", http.StatusBadRequest, "unknown level"},
+		{http.MethodPut, "/rotate?destination=file", "", http.StatusOK, `"mode":"channel","length":0,"capacity":10,"batchSize":128`},
+		{http.MethodPut, "/flush", "", http.StatusOK, `"destinations"`},
+		{http.MethodGet, "/flush", "", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/unknown", "", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		status, body := request(test.method, test.path, test.body)
+		if status != test.status || !strings.Contains(body, test.contains) {
+			t.Error("Expected", test.method, test.path, "to respond", test.status, test.contains, "- but got:", status, body)
+		}
+	}
+	server.Close()
+	Shutdown(false)
+
+	archives, _ := filepath.Glob(logFile + "_*")
+	if len(archives) != 1 {
+		t.Error("Expected the log file to be rotated - but got:", archives)
+	}
+	for _, archive := range append(archives, logFile) {
+		os.Remove(archive)
+	}
+}
+
+func TestHandlersDuringShutdown(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+
+	Startup(10)
+	ring := SetupRing("recent", 10, 0)
+	admin, rings := httptest.NewServer(AdminHandler()), httptest.NewServer(RingHandler(ring))
+	done := make(chan struct{})
+	for _, url := range []string{admin.URL, rings.URL} {
+		go func(url string) {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < 50; i++ {
+				if resp, err := http.Get(url); err == nil {
+					resp.Body.Close()
+				}
+			}
+		}(url)
+	}
+	Shutdown(false)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Expected the requests to complete after Shutdown")
+		}
+	}
+	admin.Close()
+	rings.Close()
+
+	if err := s.configure(configMessage{getstate, map[int]any{logstate: new(adminState)}}); err == nil || err.Error() != sg000 {
+		t.Error("Expected the request to fail after Shutdown - but got:", err)
+	}
+}
+
+func TestRingHandler(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+
+	Startup(10)
+	ring := SetupRing("recent", 3, 0)
+	small := SetupRing("small", 10, 80)
+	SetLevel(ring, DEBUG)
+	for i := 1; i <= 5; i++ {
+		WriteLevel(Level(i%3-1), ring|small, "record", i)
+	}
+	Sync()
+	server := httptest.NewServer(RingHandler(ring))
+	get := func(query string) string {
+		resp, err := http.Get(server.URL + query)
+		if err != nil {
+			t.Fatal("Expected no error - but got:", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return string(data)
+	}
+
+	if data := get("/"); strings.Count(data, "\n") != 3 || !strings.Contains(data, " DEBUG record 3\n") || !strings.Contains(data, " WARN record 5\n") {
+		t.Error("Expected the last 3 log records - but got:", data)
+	}
+	if data := get("/?format=json&level=warn&q=record"); strings.Count(data, "\n") != 1 || !strings.Contains(data, `"level":"WARN","msg":"record 5"}`) {
+		t.Error("Expected the filtered log records as JSON - but got:", data)
+	}
+	var smallServer = httptest.NewServer(RingHandler(small))
+	if resp, err := http.Get(smallServer.URL); err == nil {
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if strings.Count(string(data), "\n") != 1 || !strings.Contains(string(data), "record 5") {
+			t.Error("Expected the last log record only - but got:", string(data))
+		}
+	}
+	smallServer.Close()
+
+	// follow the ring buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/?follow=1&level=warn", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("Expected no error - but got:", err)
+	}
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Error("Expected Server-Sent Events - but got:", resp.Header.Get("Content-Type"))
+	}
+	events := bufio.NewReader(resp.Body)
+	WriteLevel(ERROR, ring, "live record")
+	for _, expected := range []string{" WARN record 5", "", " ERROR live record", ""} {
+		line, _ := events.ReadString('\n')
+		if !strings.HasPrefix(line, "data: ") && expected != "" || !strings.HasSuffix(line, expected+"\n") {
+			t.Error("Expected event line", expected, "- but got:", line)
+		}
+	}
+	cancel()
+	resp.Body.Close()
+	server.Close()
+	Shutdown(false)
+}
+
+func TestApplyConfigKeepsRings(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	auditFile := filepath.Join(t.TempDir(), "audit.log")
+
+	Startup(10)
+	ring := SetupRing("recent", 10, 0)
+	if err := ApplyConfig(&Config{}); err != nil {
+		t.Fatal("Expected no error - but got:", err)
+	}
+	if destination := Destination("recent"); destination != ring {
+		t.Error("Expected the ring buffer to keep its log destination", ring, "- but got:", destination)
+	}
+	// a named log file must not take over the log destination of a ring buffer
+	if _, ok := ApplyConfig(&Config{Files: map[string]*FileConfig{"recent": {Path: auditFile}}}).(*ConfigError); !ok {
+		t.Error("Expected a configuration error for a named log file with the name of a ring buffer")
+	}
+	if _, err := os.Stat(auditFile); err == nil {
+		t.Error("Expected the log file of the rejected configuration not to be opened")
+	}
+	audit := SetupLogNamed("audit", auditFile, false)
+	if audit == ring {
+		t.Error("Expected the named log file to get another log destination than the ring buffer")
+	}
+	Write(audit, "audit record")
+	Write(ring, "ring record")
+	Sync()
+
+	// file-only functions and admin requests reject ring buffers
+	func() {
+		defer func() {
+			if r := recover(); r != sg003 {
+				t.Error("Expected SwitchLogNamed to panic with", sg003, "- but got:", r)
+			}
+		}()
+		SwitchLogNamed("recent", filepath.Join(t.TempDir(), "recent.log"))
+	}()
+	server := httptest.NewServer(AdminHandler())
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/prefix?destination=recent", strings.NewReader(`["x"]`))
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Error("Expected a bad request for the prefix of a ring buffer - but got:", resp, err)
+	} else {
+		resp.Body.Close()
+	}
+	server.Close()
+
+	var r *ringBuffer
+	s.configure(configMessage{getring, map[int]any{logdestination: ring, logring: &r}})
+	Shutdown(false)
+
+	if entries := r.snapshot(); len(entries) != 1 || entries[0].rec.Msg != "ring record" {
+		t.Error("Expected the ring record only in the ring buffer - but got:", len(entries))
+	}
+	if data, _ := os.ReadFile(auditFile); string(data) != "\naudit record\n" {
+		t.Error("Expected the audit record only in the audit log file - but got:", string(data))
+	}
+}
+
+func TestFlightRecorder(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	var data strings.Builder
+	s.stdoutLogger.self = newLogger(&data)
+
+	StartupSync()
+	logger := Named("request").WithRecorder(2)
+	for i := 1; i <= 3; i++ {
+		logger.WriteLevel(DEBUG, STDOUT, "debug", i)
+	}
+	logger.Write(STDOUT, "info")
+	logger.With(Int("status", 500)).Log(ERROR, STDOUT, "failed")
+	logger.WriteLevel(TRACE, STDOUT, "trace")
+	logger.Dump()
+	logger.WriteLevel(DEBUG, STDOUT, "discarded")
+
+	ctx := NewRecorderContext(context.Background(), 10)
+	LogCtx(ctx, DEBUG, STDOUT, "context debug")
+	DumpContext(ctx)
+	Shutdown(false)
+
+	expected := "[request] info\n[request] debug 2\n[request] debug 3\n[request] failed status=500\n[request] trace\ncontext debug\n"
+	if data.String() != expected {
+		t.Error("Expected log records:", expected, "- but got:", data.String())
+	}
+
+	// a dump after Shutdown discards the log messages kept
+	for _, shards := range []int{0, 2} {
+		s = new(simpleLogService) // reset service instance
+		s.stdoutLogger.self = newLogger(&data)
+		startup(1, shards)
+		logger = WithRecorder(4)
+		for i := 0; i < 4; i++ {
+			logger.WriteLevel(DEBUG, STDOUT, "kept")
+		}
+		Shutdown(false)
+		logger.Dump()
+		if logger.recorder.count != 0 || strings.Contains(data.String(), "kept") {
+			t.Error("Expected the log messages kept to be discarded - but got:", data.String())
+		}
+	}
+}
+
+func TestLinePolicies(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	var data strings.Builder
+	s.stdoutLogger.self = newLogger(&data)
+	logFile := "test1.log"
+
+	StartupSync()
+	SetupLog(logFile, false)
+	SetPrefix(STDOUT, "[%LEVEL%]")
+	SetPrefix(FILE, "[%LEVEL%]")
+	msg := "login failed\n[ERROR] fake record\r\x1b[31mred\x1b[0m C:\\n"
+	Write(STDOUT, msg)
+	SetLinePolicy(STDOUT, EscapeLines)
+	Write(STDOUT, msg)
+	Write(STDOUT, `C:\new`)
+	WriteStack(STDOUT, "stack")
+	SetLinePolicy(STDOUT, IndentLines)
+	Named("auth").Write(STDOUT, "first\nsecond")
+	SetStripANSI(FILE, true)
+	Log(INFO, FILE, "\x1b[1mbold\x1b[0m \x1b]0;title\x07done", String("user", "\x1b[31m"))
+	Shutdown(false)
+
+	expected := "[INFO] " + msg + "\n" +
+		"[INFO] login failed\\n[ERROR] fake record\\r\\x1b[31mred\\x1b[0m C:\\\\n\n" +
+		"[INFO] C:\\\\new\n" +
+		"[INFO] [auth] first\n              second\n"
+	// under EscapeLines, the stack trace is an escaped field of the single log record line
+	_, stackRecord, _ := strings.Cut(data.String(), "[INFO] stack ")
+	stackRecord, _, _ = strings.Cut("[INFO] stack "+stackRecord, "\n")
+	if !strings.HasPrefix(stackRecord, `[INFO] stack stack="`) || !strings.Contains(stackRecord, `TestLinePolicies()\n\t`) {
+		t.Errorf("Expected the stack trace as escaped field - but got: %q", stackRecord)
+	}
+	expected = strings.Replace(expected, "[INFO] [auth]", stackRecord+"\n[INFO] [auth]", 1)
+	if data.String() != expected {
+		t.Errorf("Expected log records:\n%q - but got:\n%q", expected, data.String())
+	}
+	expected = "[INFO] bold done user=\"\\u001b[31m\"\n"
+	if data, err := os.ReadFile(logFile); err != nil {
+		t.Error("Expected to find file", logFile, "- but got:", err)
+	} else if !strings.HasSuffix(string(data), expected) {
+		t.Errorf("Expected log record %q - but got: %q", expected, string(data))
+	} else {
+		os.Remove(logFile)
+	}
+}
+
+func TestMaxRecordSize(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	var data strings.Builder
+	s.stdoutLogger.self = newLogger(&data)
+
+	StartupSync()
+	SetMaxRecordSize(STDOUT, 10)
+	Write(STDOUT, "short")
+	Write(STDOUT, "ääääääääää")
+	Write(STDOUT, strings.Repeat("x", 1<<20))
+	if c := cap(s.stdoutLogger.self.lineBuf); c > maxRetainedBufSize {
+		t.Error("Expected the line buffer to be released - but its capacity is:", c)
+	}
+	SetMaxRecordSize(STDOUT, 0)
+	Write(STDOUT, "no limit anymore")
+	Shutdown(false)
+
+	expected := "short\näääää…[truncated 10 bytes]\nxxxxxxxxxx…[truncated 1048566 bytes]\nno limit anymore\n"
+	if data.String() != expected {
+		t.Errorf("Expected log records:\n%q - but got:\n%q", expected, data.String())
+	}
+}
+
+func TestColorOutput(t *testing.T) {
+	s = new(simpleLogService) // reset service instance
+	var data strings.Builder
+	s.stdoutLogger.self = newLogger(&data)
+
+	StartupSync()
+	SetPrefix(STDOUT, "#2006#", "[%LEVEL%]")
+	SetFormatter(STDOUT, TextFormatter{Color: ColorAlways})
+	SetLinePolicy(STDOUT, IndentLines)
+	Named("db").Log(WARN, STDOUT, "slow\nquery", Int("ms", 1200))
+	SetFormatter(STDOUT, TextFormatter{Color: ColorNever})
+	Write(STDOUT, "plain")
+	Shutdown(false)
+
+	year := time.Now().Format("2006")
+	expected := "\x1b[2m" + year + "\x1b[0m [\x1b[33mWARN\x1b[0m] \x1b[1m[db]\x1b[0m slow\n" + strings.Repeat(" ", 17) + "query ms=1200\n" +
+		year + " [INFO] plain\n"
+	if data.String() != expected {
+		t.Errorf("Expected log records:\n%q - but got:\n%q", expected, data.String())
+	}
+
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	if detectColor(devNull, "") {
+		t.Error("Expected no color for a character device which isn't a terminal")
+	}
+	if pty, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0); err == nil {
+		if !detectColor(pty, "") {
+			t.Error("Expected color for a terminal")
+		}
+		if detectColor(pty, "1") {
+			t.Error("Expected no color if NO_COLOR is set")
+		}
+		pty.Close()
+	}
+	logFile, _ := os.Create("test1.log")
+	defer os.Remove("test1.log")
+	defer logFile.Close()
+	if detectColor(logFile, "") {
+		t.Error("Expected no color for a regular file")
+	}
+
+	// log files are never colored
+	cfg := &Config{File: &FileConfig{DestinationConfig: DestinationConfig{Color: "auto"}, Path: "test1.log"}}
+	if err, ok := cfg.Validate().(*ConfigError); !ok || len(err.Problems) != 1 || err.Problems[0] != "file.color: only supported by stdout" {
+		t.Error("Expected the color mode of the log file to be rejected - but got:", err)
+	}
+	StartupSync()
+	SetupLog("test1.log", false)
+	func() {
+		defer func() {
+			if r := recover(); r != sg008 {
+				t.Error("Expected SetFormatter to panic with", sg008, "- but got:", r)
+			}
+		}()
+		SetFormatter(FILE, &TextFormatter{Color: ColorAuto})
+	}()
+	Shutdown(false)
+}
+
 func BenchmarkLog(b *testing.B) {
 	s = new(simpleLogService) // reset service instance
 	logFile := "test1.log"
@@ -244,3 +1408,107 @@ func BenchmarkLog(b *testing.B) {
 		os.Remove(logFile)
 	}
 }
+
+func benchmarkDestination(b *testing.B, destination int, write func(destination int)) {
+	s = new(simpleLogService) // reset service instance
+	stdOut := os.Stdout
+	logFile := "test1.log"
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+	os.Stdout, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+
+	Startup(100)
+	SetupLog(logFile, false)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		write(destination)
+	}
+	Shutdown(false)
+
+	os.Stdout.Close()
+	os.Stdout = stdOut
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+}
+
+func BenchmarkWriteStdout(b *testing.B) {
+	benchmarkDestination(b, STDOUT, func(destination int) { Write(destination, "The answer to all questions is", 42) })
+}
+
+func BenchmarkWriteFile(b *testing.B) {
+	benchmarkDestination(b, FILE, func(destination int) { Write(destination, "The answer to all questions is", 42) })
+}
+
+func BenchmarkWriteMulti(b *testing.B) {
+	benchmarkDestination(b, MULTI, func(destination int) { Write(destination, "The answer to all questions is", 42) })
+}
+
+func BenchmarkLogStdout(b *testing.B) {
+	benchmarkDestination(b, STDOUT, func(destination int) {
+		Log(INFO, destination, "The answer to all questions", String("question", "all"), Int("answer", 42), Float64("confidence", 0.99))
+	})
+}
+
+func BenchmarkLogFile(b *testing.B) {
+	benchmarkDestination(b, FILE, func(destination int) {
+		Log(INFO, destination, "The answer to all questions", String("question", "all"), Int("answer", 42), Float64("confidence", 0.99))
+	})
+}
+
+func BenchmarkLogMulti(b *testing.B) {
+	benchmarkDestination(b, MULTI, func(destination int) {
+		Log(INFO, destination, "The answer to all questions", String("question", "all"), Int("answer", 42), Float64("confidence", 0.99))
+	})
+}
+
+func BenchmarkLogFileOverrides(b *testing.B) {
+	benchmarkDestination(b, FILE, func(destination int) {
+		if s.levelOverrides.Load() == nil {
+			SetLevelOverrides("http*=debug,db=warn")
+		}
+		Log(INFO, destination, "The answer to all questions", String("question", "all"), Int("answer", 42), Float64("confidence", 0.99))
+	})
+}
+
+func BenchmarkLoggerFile(b *testing.B) {
+	logger := Named("answers").With(String("question", "all"))
+	benchmarkDestination(b, FILE, func(destination int) {
+		logger.Log(INFO, destination, "The answer to all questions", Int("answer", 42), Float64("confidence", 0.99))
+	})
+}
+
+func benchmarkQueue(b *testing.B, shards int) {
+	s = new(simpleLogService) // reset service instance
+	logFile := "test1.log"
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+
+	startup(1000, shards)
+	SetupLog(logFile, false)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Log(INFO, FILE, "The answer to all questions", Int("answer", 42))
+		}
+	})
+	Shutdown(false)
+
+	if _, err := os.Stat(logFile); err == nil {
+		os.Remove(logFile)
+	}
+}
+
+func BenchmarkParallelChannel(b *testing.B) {
+	benchmarkQueue(b, 0)
+}
+
+func BenchmarkParallelSharded(b *testing.B) {
+	benchmarkQueue(b, runtime.GOMAXPROCS(0))
+}
@@ -0,0 +1,82 @@
+package simplelog
+
+// Logger represents a child logger which carries a component name and bound fields, which are added to every
+// log record it writes. A Logger is lightweight: it shares the log service, its queue and its log destinations
+// with all other loggers and with the package level write functions. Loggers are immutable, hence they can
+// be used simultaneously from multiple goroutines.
+// Loggers are created by With and Named, e.g.:
+//
+//	httpLog := simplelog.Named("http").With(simplelog.String("remote", addr))
+//	httpLog.Write(simplelog.FILE, "request received")
+type Logger struct {
+	component string    // the component name, which is added to every log record
+	fields    []Field   // the bound fields, which are added to every log record
+	recorder  *recorder // the flight recorder, see WithRecorder; nil if the logger has none
+}
+
+// With returns a logger with bound fields, which are added to every log record it writes.
+// The fields parameter consists of zero or more fields that are bound to the logger.
+func With(fields ...Field) *Logger {
+	return new(Logger).With(fields...)
+}
+
+// Named returns a logger with a component name, which is added to every log record it writes.
+// The name parameter specifies the component name, e.g. "http".
+func Named(name string) *Logger {
+	return new(Logger).Named(name)
+}
+
+// WithRecorder returns a logger with a flight recorder, which keeps DEBUG and TRACE log messages until an ERROR
+// log message is written, see the method WithRecorder.
+// The size parameter specifies the maximum number of log messages kept.
+func WithRecorder(size int) *Logger {
+	return new(Logger).WithRecorder(size)
+}
+
+// With returns a child logger, which has the component name, the bound fields and the flight recorder of the logger
+// and additionally the given bound fields.
+// The fields parameter consists of zero or more fields that are bound to the child logger.
+func (l *Logger) With(fields ...Field) *Logger {
+	return &Logger{component: l.component, fields: append(l.fields[:len(l.fields):len(l.fields)], fields...), recorder: l.recorder}
+}
+
+// Named returns a child logger, which has the bound fields and the flight recorder of the logger and whose component
+// name is the given name appended to the component name of the logger, separated by a dot, e.g. "http.auth".
+// The name parameter specifies the component name of the child logger.
+func (l *Logger) Named(name string) *Logger {
+	if l.component != "" {
+		name = l.component + "." + name
+	}
+	return &Logger{component: name, fields: l.fields, recorder: l.recorder}
+}
+
+// Write writes a log message with the component name and the bound fields of the logger to a specified destination.
+// The log message is written with level INFO, see the package level function Write.
+func (l *Logger) Write(destination int, values ...any) {
+	write(nil, l, INFO, destination, "", values, "")
+}
+
+// WriteLevel writes a log message with a given level and with the component name and the bound fields of the logger
+// to a specified destination, see the package level function WriteLevel.
+func (l *Logger) WriteLevel(level Level, destination int, values ...any) {
+	write(nil, l, level, destination, "", values, "")
+}
+
+// Writef writes a log message, which is formatted according to a format specifier, with the component name and
+// the bound fields of the logger to a specified destination, see the package level function Writef.
+func (l *Logger) Writef(destination int, format string, values ...any) {
+	write(nil, l, INFO, destination, format, values, "")
+}
+
+// WriteStack writes a log message together with the stack trace of the calling goroutine and with the component
+// name and the bound fields of the logger to a specified destination, see the package level function WriteStack.
+func (l *Logger) WriteStack(destination int, values ...any) {
+	write(nil, l, INFO, destination, "", values, captureStack(1))
+}
+
+// Log writes a log message with a given level, the component name of the logger and typed fields to a specified
+// destination. The bound fields of the logger are written in front of the given fields, see the package level
+// function Log.
+func (l *Logger) Log(level Level, destination int, msg string, fields ...Field) {
+	log(nil, l, level, destination, msg, fields)
+}
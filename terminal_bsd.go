@@ -0,0 +1,10 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package simplelog
+
+import (
+	"syscall"
+)
+
+// ioctlReadTermios is the ioctl request which reads the terminal attributes.
+const ioctlReadTermios = syscall.TIOCGETA
@@ -0,0 +1,8 @@
+package simplelog
+
+import (
+	"syscall"
+)
+
+// ioctlReadTermios is the ioctl request which reads the terminal attributes.
+const ioctlReadTermios = syscall.TCGETS
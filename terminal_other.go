@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package simplelog
+
+// isTerminal returns false, since terminals can't be detected on this platform.
+func isTerminal(fd uintptr) bool {
+	return false
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package simplelog
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// isTerminal returns true, if the file descriptor refers to a terminal, i.e. if its terminal attributes
+// can be read. Other character devices, e.g. /dev/null, are no terminals.
+func isTerminal(fd uintptr) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlReadTermios, uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package simplelog
+
+import (
+	"syscall"
+)
+
+// isTerminal returns true, if the file handle refers to a console.
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(fd), &mode) == nil
+}